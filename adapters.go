@@ -0,0 +1,63 @@
+package iterator
+
+import "container/heap"
+
+// Sortable is a view over the values collected from an iterator that implements sort.Interface, ordering them using a less
+// function. The methods work directly on Values, so sorting them doesn't need an extra copy.
+type Sortable[T any] struct {
+	Values []T
+	less   func(a, b T) bool
+}
+
+// AsSortable collects the given iterator, applying all of the chained operations, and returns a sort.Interface view over
+// the result that orders values using the given less function.
+func AsSortable[T any](it Of[T], less func(a, b T) bool) *Sortable[T] {
+	return &Sortable[T]{Values: it.Collect(), less: less}
+}
+
+func (s *Sortable[T]) Len() int {
+	return len(s.Values)
+}
+
+func (s *Sortable[T]) Less(i, j int) bool {
+	return s.less(s.Values[i], s.Values[j])
+}
+
+func (s *Sortable[T]) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+}
+
+// Heap is a view over the values collected from an iterator that implements heap.Interface, with the value that comes
+// first according to the less function at the top. Use it with the functions of the container/heap package.
+type Heap[T any] struct {
+	Sortable[T]
+}
+
+// AsHeap collects the given iterator, applying all of the chained operations, and returns a heap.Interface view over the
+// result, already initialized with heap.Init so the value that comes first according to the less function is on top.
+func AsHeap[T any](it Of[T], less func(a, b T) bool) *Heap[T] {
+	h := &Heap[T]{Sortable[T]{Values: it.Collect(), less: less}}
+	heap.Init(h)
+	return h
+}
+
+// Push appends x, which must be a T, to the values. It's meant to be called by heap.Push rather than directly.
+func (h *Heap[T]) Push(x any) {
+	h.Values = append(h.Values, x.(T))
+}
+
+// Pop removes and returns the last value. It's meant to be called by heap.Pop rather than directly.
+func (h *Heap[T]) Pop() any {
+	last := h.Values[len(h.Values)-1]
+	h.Values[len(h.Values)-1] = *new(T) // don't keep a reference to the popped value around
+	h.Values = h.Values[:len(h.Values)-1]
+	return last
+}
+
+// ByKey returns a less function that orders values by the key extracted from them, in ascending order. It can be passed to
+// Sort, AsSortable, AsHeap, or anything else taking a less function.
+func ByKey[T any, K Ordered](key func(T) K) func(a, b T) bool {
+	return func(a, b T) bool {
+		return key(a) < key(b)
+	}
+}
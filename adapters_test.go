@@ -0,0 +1,43 @@
+package iterator_test
+
+import (
+	"container/heap"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+type task struct {
+	name     string
+	priority int
+}
+
+func Test_AsSortable(t *testing.T) {
+	tasks := []task{{"b", 2}, {"c", 3}, {"a", 1}, {"skip", 0}}
+	s := iterator.AsSortable(iterator.From(tasks).Filter(func(t task) bool {
+		return t.priority > 0
+	}), iterator.ByKey(func(t task) string {
+		return t.name
+	}))
+	sort.Sort(s)
+	expected := []task{{"a", 1}, {"b", 2}, {"c", 3}}
+	if !reflect.DeepEqual(s.Values, expected) {
+		t.Errorf("expected %+v, got %+v", expected, s.Values)
+	}
+}
+
+func Test_AsHeap(t *testing.T) {
+	h := iterator.AsHeap(iterator.From([]task{{"b", 2}, {"c", 3}, {"a", 1}}), func(a, b task) bool {
+		return a.priority > b.priority
+	})
+	heap.Push(h, task{"d", 4})
+	result := []string{}
+	for h.Len() > 0 {
+		result = append(result, heap.Pop(h).(task).name)
+	}
+	if !reflect.DeepEqual(result, []string{"d", "c", "b", "a"}) {
+		t.Errorf("expected [d c b a], got %v", result)
+	}
+}
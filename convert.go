@@ -0,0 +1,54 @@
+package iterator
+
+import "unsafe"
+
+// BytesToStrings returns a new iterator that converts each value left after applying the chained operations of the given
+// iterator from a []byte to a string. The conversion is lazy, so values are only converted as they are consumed. By default
+// each value is copied, as with a regular conversion; see the ZeroCopy option for avoiding the copy.
+func BytesToStrings(it Of[[]byte], opts ...ConvertOption) Of[string] {
+	options := new(convertOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	conv := func(b []byte) string {
+		return string(b)
+	}
+	if options.zeroCopy {
+		conv = func(b []byte) string {
+			return unsafe.String(unsafe.SliceData(b), len(b))
+		}
+	}
+	return convert(it, conv)
+}
+
+// StringsToBytes returns a new iterator that converts each value left after applying the chained operations of the given
+// iterator from a string to a []byte. The conversion is lazy, so values are only converted as they are consumed. By default
+// each value is copied, as with a regular conversion; see the ZeroCopy option for avoiding the copy.
+func StringsToBytes(it Of[string], opts ...ConvertOption) Of[[]byte] {
+	options := new(convertOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	conv := func(s string) []byte {
+		return []byte(s)
+	}
+	if options.zeroCopy {
+		conv = func(s string) []byte {
+			return unsafe.Slice(unsafe.StringData(s), len(s))
+		}
+	}
+	return convert(it, conv)
+}
+
+// convert returns a new iterator over the values left after applying the chained operations of the given iterator,
+// converted to another type using fn. It's how operations that change the element type are implemented, as methods can't
+// have type parameters of their own.
+func convert[T, U any](it Of[T], fn func(T) U) Of[U] {
+	return FromSeq(func(yield func(U) bool) {
+		for val := range it.Seq() {
+			if !yield(fn(val)) {
+				return
+			}
+		}
+	})
+}
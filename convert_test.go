@@ -0,0 +1,43 @@
+package iterator_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_BytesToStrings(t *testing.T) {
+	lines := [][]byte{[]byte("GET /"), []byte(""), []byte("POST /login")}
+	for name, opts := range map[string][]iterator.ConvertOption{
+		"copy":      nil,
+		"zero copy": {iterator.ZeroCopy(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.From(lines).Filter(func(line []byte) bool {
+				return len(line) > 0
+			})
+			result := iterator.BytesToStrings(it, opts...).Collect()
+			if !reflect.DeepEqual(result, []string{"GET /", "POST /login"}) {
+				t.Errorf("expected [GET / POST /login], got %v", result)
+			}
+		})
+	}
+}
+
+func Test_StringsToBytes(t *testing.T) {
+	for name, opts := range map[string][]iterator.ConvertOption{
+		"copy":      nil,
+		"zero copy": {iterator.ZeroCopy(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.StringsToBytes(iterator.From([]string{"a", "bc"}), opts...).Filter(func(b []byte) bool {
+				return bytes.HasPrefix(b, []byte("b"))
+			}).Collect()
+			if !reflect.DeepEqual(result, [][]byte{[]byte("bc")}) {
+				t.Errorf("expected [bc], got %q", result)
+			}
+		})
+	}
+}
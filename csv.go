@@ -0,0 +1,47 @@
+package iterator
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FromCSV returns a new iterator over the records of the CSV data read from r, reading one record at a time as they are
+// requested, so files much larger than memory can be processed. The data is parsed by encoding/csv with its defaults, and
+// each record is a new slice, so it can be kept after the next one is read. A header row is returned like any other
+// record. If the data can't be parsed, the iteration ends and the error is reported by TryCollect and TryForEach. As with
+// FromFunc, Reset has no effect on the elements returned. The options are the same as for From, although CopySource has
+// no effect.
+func FromCSV(r io.Reader, opts ...FromOption) Of[[]string] {
+	cr := csv.NewReader(r)
+	done := false
+	return newIter(nil, func(it *iter[[]string]) ([]string, bool) {
+		if done {
+			return nil, false
+		}
+		record, err := cr.Read()
+		if err != nil {
+			done = true
+			if !errors.Is(err, io.EOF) {
+				it.err = fmt.Errorf("iterator: reading CSV: %w", err)
+			}
+			return nil, false
+		}
+		it.nextIndex++
+		return record, true
+	}, 0, opts)
+}
+
+// ToCSV applies all of the chained operations to the iterator and writes each record left to w as a line of CSV, one at a
+// time, using encoding/csv with its defaults. ToCSV stops at the first error, whether it comes from a TryMap or TryFilter
+// function, the source, or writing, and returns it. Records written before the error are flushed to w.
+func ToCSV(it Of[[]string], w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := tryEach(it, cw.Write)
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
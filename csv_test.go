@@ -0,0 +1,62 @@
+package iterator_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_FromCSV(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected [][]string
+		err      string
+	}{
+		"records": {
+			input:    "name,age\nana,23\n\"luis, jr\",24\n",
+			expected: [][]string{{"name", "age"}, {"ana", "23"}, {"luis, jr", "24"}},
+		},
+		"empty": {
+			input:    "",
+			expected: [][]string{},
+		},
+		"parse_error": {
+			input: "name,age\nana,23,extra\n",
+			err:   "iterator: reading CSV: record on line 2: wrong number of fields",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := iterator.FromCSV(strings.NewReader(test.input)).TryCollect()
+			if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+			if test.err == "" && !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+	var parseErr *csv.ParseError
+	if _, err := iterator.FromCSV(strings.NewReader("a,\"b\n")).TryCollect(); !errors.As(err, &parseErr) {
+		t.Errorf("expected a wrapped *csv.ParseError, got %v", err)
+	}
+}
+
+func Test_ToCSV(t *testing.T) {
+	it := iterator.FromCSV(strings.NewReader("name,age\nana,23\nluis,17\n")).Filter(func(record []string) bool {
+		return record[1] != "17"
+	}).Map(func(record []string) []string {
+		return []string{record[0]}
+	})
+	out := new(strings.Builder)
+	if err := iterator.ToCSV(it, out); err != nil || out.String() != "name\nana\n" {
+		t.Errorf("expected %q, got %q and %v", "name\nana\n", out.String(), err)
+	}
+	if err := iterator.ToCSV(iterator.FromValues([]string{"a"}), failingWriter{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package iterator
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// FromRows returns a new iterator over the rows of a query result, using scan to read each row into a value as it's
+// requested, so large results can be filtered, mapped, and streamed without loading every row into a slice first. scan
+// is called after rows.Next has returned true, so it only needs to call rows.Scan. The rows are closed once they are
+// exhausted, once scan fails, or when the Stop or Close method is called, so call Close when abandoning the iterator
+// early to also learn whether closing them failed. If scan or the iteration fails, the iterator ends there, and the
+// error is returned by TryCollect and TryForEach; other methods only see the rows before it. As with FromFunc, Reset
+// has no effect on the elements returned. The options are the same as for From, although CopySource has no effect.
+func FromRows[T any](rows *sql.Rows, scan func(*sql.Rows) (T, error), opts ...FromOption) Of[T] {
+	done := false
+	it := newIter(nil, func(it *iter[T]) (T, bool) {
+		var val T
+		if done {
+			return val, false
+		}
+		if !rows.Next() {
+			done = true
+			if err := rows.Err(); err != nil {
+				it.err = fmt.Errorf("iterator: reading row %d: %w", it.nextIndex, err)
+			}
+			rows.Close()
+			return val, false
+		}
+		val, err := scan(rows)
+		if err != nil {
+			done, it.err = true, fmt.Errorf("iterator: scanning row %d: %w", it.nextIndex, err)
+			rows.Close()
+			return *new(T), false
+		}
+		it.nextIndex++
+		return val, true
+	}, 0, opts)
+	var mu sync.Mutex // with the Prefetch option, the rows are closed by the goroutine reading ahead once it stops
+	var closeErr error
+	it.onStop(func() {
+		err := rows.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			closeErr = fmt.Errorf("iterator: closing rows: %w", err)
+		}
+	})
+	it.closeErr = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return closeErr
+	}
+	return it
+}
@@ -0,0 +1,169 @@
+package iterator_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+// fakeDriver serves every query with the rows encoded in the query itself, separated by semicolons, so tests don't need
+// a real database. A row reading "fail" makes the iteration fail there, and one reading "leak" makes closing the rows fail.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt(query), nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions aren't supported")
+}
+
+type fakeStmt string
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return 0
+}
+
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec isn't supported")
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if s != "" {
+		rows.values = strings.Split(string(s), ";")
+	}
+	rows.leak = strings.Contains(string(s), "leak")
+	return rows, nil
+}
+
+type fakeRows struct {
+	values []string
+	leak   bool
+}
+
+var fakeRowsClosed int // the number of times any fakeRows has been closed
+
+func (*fakeRows) Columns() []string {
+	return []string{"name"}
+}
+
+func (r *fakeRows) Close() error {
+	fakeRowsClosed++
+	if r.leak {
+		return errors.New("cursor leaked")
+	}
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	val := r.values[0]
+	r.values = r.values[1:]
+	if val == "fail" {
+		return errors.New("connection lost")
+	}
+	dest[0] = val
+	return nil
+}
+
+func init() {
+	sql.Register("iterator_fake", fakeDriver{})
+}
+
+func Test_FromRows(t *testing.T) {
+	db, err := sql.Open("iterator_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	scanName := func(rows *sql.Rows) (string, error) {
+		var name string
+		err := rows.Scan(&name)
+		return name, err
+	}
+	query := func(q string) *sql.Rows {
+		t.Helper()
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	tests := map[string]struct {
+		query    string
+		expected []string
+		err      string
+	}{
+		"rows":       {"ana;beto;ana;carla", []string{"ana", "beto", "carla"}, ""},
+		"empty":      {"", []string{}, ""},
+		"iteration":  {"ana;fail;beto", nil, "iterator: reading row 1: connection lost"},
+		"scan_error": {"ana;", nil, "iterator: scanning row 1: empty name"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			closed := fakeRowsClosed
+			result, err := iterator.FromRows(query(test.query), func(rows *sql.Rows) (string, error) {
+				name, err := scanName(rows)
+				if err == nil && name == "" {
+					err = errors.New("empty name")
+				}
+				return name, err
+			}).Unique().TryCollect()
+			if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+			if fakeRowsClosed != closed+1 {
+				t.Error("expected the rows to be closed")
+			}
+		})
+	}
+
+	closed := fakeRowsClosed
+	it := iterator.FromRows(query("ana;beto"), scanName)
+	if val, ok := it.Next(); !ok || val != "ana" {
+		t.Errorf("expected ana, got %q", val)
+	}
+	it.Stop()
+	if fakeRowsClosed != closed+1 {
+		t.Error("expected Stop to close the rows")
+	}
+
+	it = iterator.FromRows(query("ana;leak"), scanName)
+	it.Next()
+	if err := it.Close(); err == nil || err.Error() != "iterator: closing rows: cursor leaked" {
+		t.Errorf("expected Close to report the error from closing the rows, got %v", err)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("expected Close to end the iteration")
+	}
+	if err := iterator.From([]int{1, 2}).Close(); err != nil {
+		t.Errorf("expected closing an iterator over a slice to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package iterator
+
+// EntryKeys returns a new iterator over the keys of the entries of the given iterator, such as one created by FromMap or
+// FromSeq2, after applying its chained operations. The entries are read as keys are requested.
+func EntryKeys[K comparable, V any](it Of[Entry[K, V]]) Of[K] {
+	return FromSeq(func(yield func(K) bool) {
+		for e := range it.Seq() {
+			if !yield(e.Key) {
+				return
+			}
+		}
+	})
+}
+
+// EntryValues returns a new iterator over the values of the entries of the given iterator, after applying its chained
+// operations. The entries are read as values are requested.
+func EntryValues[K comparable, V any](it Of[Entry[K, V]]) Of[V] {
+	return FromSeq(func(yield func(V) bool) {
+		for e := range it.Seq() {
+			if !yield(e.Value) {
+				return
+			}
+		}
+	})
+}
+
+// MapValues returns a new iterator over the entries of the given iterator, after applying its chained operations, with
+// each value replaced by the result of calling fn on it. The keys are left as they are, and fn can change the type of the
+// values. The entries are read as they are requested.
+func MapValues[K comparable, V, W any](it Of[Entry[K, V]], fn func(V) W) Of[Entry[K, W]] {
+	return FromSeq(func(yield func(Entry[K, W]) bool) {
+		for e := range it.Seq() {
+			if !yield(Entry[K, W]{Key: e.Key, Value: fn(e.Value)}) {
+				return
+			}
+		}
+	})
+}
+
+// FilterKeys chains a Filter to the given iterator that keeps only the entries whose key fn returns true for, returning the
+// iterator. Unlike the other entry helpers, it chains to the iterator itself, so the options it was created with still
+// apply.
+func FilterKeys[K comparable, V any](it Of[Entry[K, V]], fn func(K) bool) Of[Entry[K, V]] {
+	return it.Filter(func(e Entry[K, V]) bool {
+		return fn(e.Key)
+	})
+}
+
+// ToMap applies all of the chained operations to the iterator and builds a map from the entries left. A later entry
+// overwrites an earlier one with the same key; use CollectMap with the Duplicates option for the other policies.
+func ToMap[K comparable, V any](it Of[Entry[K, V]]) map[K]V {
+	m := make(map[K]V)
+	for e := range it.Seq() {
+		m[e.Key] = e.Value
+	}
+	return m
+}
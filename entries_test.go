@@ -0,0 +1,49 @@
+package iterator_test
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Entries(t *testing.T) {
+	ages := map[string]int{"ana": 34, "beto": 17, "caro": 52}
+	byKey := func(it iterator.Of[iterator.Entry[string, int]]) iterator.Of[iterator.Entry[string, int]] {
+		return it.Sort(func(a, b iterator.Entry[string, int]) bool {
+			return a.Key < b.Key
+		})
+	}
+
+	if keys := iterator.EntryKeys(byKey(iterator.FromMap(ages))).Collect(); !reflect.DeepEqual(keys, []string{"ana", "beto", "caro"}) {
+		t.Errorf("expected [ana beto caro], got %v", keys)
+	}
+	if values := iterator.EntryValues(byKey(iterator.FromMap(ages))).Collect(); !reflect.DeepEqual(values, []int{34, 17, 52}) {
+		t.Errorf("expected [34 17 52], got %v", values)
+	}
+
+	adults := iterator.MapValues(iterator.FromMap(ages).Filter(func(e iterator.Entry[string, int]) bool {
+		return e.Value >= 18
+	}), func(age int) bool {
+		return age >= 50
+	})
+	if result := iterator.ToMap(adults); !reflect.DeepEqual(result, map[string]bool{"ana": false, "caro": true}) {
+		t.Errorf("expected map[ana:false caro:true], got %v", result)
+	}
+
+	names := iterator.FilterKeys(iterator.FromMap(ages), func(name string) bool {
+		return strings.HasPrefix(name, "c") || strings.HasPrefix(name, "b")
+	})
+	keys := iterator.EntryKeys(names).Collect()
+	slices.Sort(keys)
+	if !reflect.DeepEqual(keys, []string{"beto", "caro"}) {
+		t.Errorf("expected [beto caro], got %v", keys)
+	}
+
+	repeated := iterator.From([]iterator.Entry[string, int]{{Key: "a", Value: 1}, {Key: "a", Value: 2}})
+	if result := iterator.ToMap(repeated); !reflect.DeepEqual(result, map[string]int{"a": 2}) {
+		t.Errorf("expected later entries to overwrite earlier ones, got %v", result)
+	}
+}
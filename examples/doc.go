@@ -0,0 +1,3 @@
+// Package examples contains runnable, end-to-end examples of common pipeline patterns built with the iterator package. The
+// examples are verified by go test, so they're guaranteed to stay in sync with the API. The package exports nothing.
+package examples
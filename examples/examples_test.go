@@ -0,0 +1,84 @@
+package examples_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thezmc/iterator"
+)
+
+// Reads people from CSV, keeps the adults, and writes them out as JSON. FromFunc pulls one row at a time from the CSV
+// reader, so the input never has to be loaded into a slice of rows.
+func Example_csvToJSON() {
+	input := strings.NewReader("name,age\nFelicita,23\nMateo,12\nLuis,24\n")
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	rows := csv.NewReader(input)
+	if _, err := rows.Read(); err != nil { // skip the header
+		panic(err)
+	}
+	people := iterator.FromFunc(func() (person, bool) {
+		record, err := rows.Read()
+		if err != nil {
+			return person{}, false
+		}
+		age, err := strconv.Atoi(record[1])
+		if err != nil {
+			return person{}, false
+		}
+		return person{Name: record[0], Age: age}, true
+	})
+	adults := people.Filter(func(p person) bool {
+		return p.Age >= 18
+	}).Collect()
+	if err := json.NewEncoder(os.Stdout).Encode(adults); err != nil {
+		panic(err)
+	}
+	// Output:
+	// [{"name":"Felicita","age":23},{"name":"Luis","age":24}]
+}
+
+// Deduplicates events arriving on a channel, such as from a message queue consumer, and passes the unique ones on to
+// another channel.
+func Example_streamingDedup() {
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		for _, id := range []string{"a", "b", "a", "c", "b"} {
+			events <- id
+		}
+	}()
+	for id := range iterator.FromChannel(events).Unique().CollectChannel() {
+		fmt.Println(id)
+	}
+	// Output:
+	// a
+	// b
+	// c
+}
+
+// Enriches records using a slow lookup on multiple goroutines. The results are collected in source order regardless of
+// which goroutine processed them.
+func Example_parallelEnrichment() {
+	type user struct {
+		ID      int
+		Country string
+	}
+	lookupCountry := func(id int) string { // stands in for a database or API call
+		return []string{"AR", "CL", "MX"}[id%3]
+	}
+	users := []user{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	enriched := iterator.From(users, iterator.Parallel(4)).Map(func(u user) user {
+		u.Country = lookupCountry(u.ID)
+		return u
+	}).Collect()
+	fmt.Println(enriched)
+	// Output:
+	// [{1 CL} {2 MX} {3 AR} {4 CL}]
+}
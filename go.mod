@@ -1,3 +1,3 @@
 module github.com/thezmc/iterator
 
-go 1.18
+go 1.23
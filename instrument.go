@@ -0,0 +1,181 @@
+package iterator
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OpStats describes the work done by one of the operations chained to an iterator during a single terminal operation, such
+// as Collect, as reported to the function passed to the Instrument option.
+type OpStats struct {
+	Stage    int           // the position of the operation in the pipeline, starting at 0 for the first operation chained
+	Name     string        // the name given to the operation using Named, or "" if it wasn't named
+	In       int           // the number of elements passed to the operation
+	Out      int           // the number of elements the operation passed on, which excludes those it filtered out or failed on
+	Duration time.Duration // the total time spent in the operation. With a parallel option, this is summed over every worker.
+}
+
+// stage is an operation or barrier of an instrumented pipeline. The counters are updated atomically, as the operations may
+// be applied on several goroutines at once when collecting in parallel.
+type stage struct {
+	index          int     // the position of the stage in the pipeline
+	name           string  // the name given to the stage using Named, if any
+	tracer         *tracer // where each element is traced to, or nil if the Trace option isn't used
+	in, out, nanos atomic.Int64
+}
+
+// label returns how the stage is referred to in traces.
+func (s *stage) label() string {
+	if s.name == "" {
+		return fmt.Sprintf("stage %d", s.index)
+	}
+	return fmt.Sprintf("stage %d (%s)", s.index, s.name)
+}
+
+// tracer writes the lines of a trace, one at a time, as the operations may be applied on several goroutines at once.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// printf writes a line to the trace. Errors from the writer are ignored, as tracing mustn't change the outcome of the
+// terminal operation.
+func (tr *tracer) printf(format string, args ...any) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	fmt.Fprintf(tr.w, format+"\n", args...)
+}
+
+// instrumented returns a copy of the given pipeline whose operations and barriers record their statistics and trace the
+// elements passing through them, along with a function reporting the statistics to the hook set by the Instrument option,
+// which the terminal operation calls once it's done. If neither Instrument nor Trace is used, the pipeline is returned
+// unchanged along with a function doing nothing.
+func (it *iter[T]) instrumented(p *pipeline[T]) (*pipeline[T], func()) {
+	if it.instrument == nil && it.trace == nil {
+		return p, func() {}
+	}
+	var tr *tracer
+	if it.trace != nil {
+		tr = &tracer{w: it.trace}
+	}
+	stages := make([]stage, p.stages())
+	for idx := range stages {
+		stages[idx].index, stages[idx].name, stages[idx].tracer = idx, p.names[idx], tr
+	}
+	wrapped := &pipeline[T]{
+		operations: make([]func(*maybe[T]), len(p.operations)),
+		barriers:   make([]barrier[T], len(p.barriers)),
+		sequential: p.sequential,
+		preserving: p.preserving,
+	}
+	next, b := 0, 0
+	for idx := 0; idx <= len(p.operations); idx++ { // stages are numbered in the order the operations and barriers were chained
+		for ; b < len(p.barriers) && p.barriers[b].after == idx; b++ {
+			wrapped.barriers[b] = p.barriers[b]
+			wrapped.barriers[b].fn = instrumentBarrier(&stages[next], p.barriers[b].fn)
+			next++
+		}
+		if idx < len(p.operations) {
+			wrapped.operations[idx] = instrumentOperation(&stages[next], p.operations[idx])
+			next++
+		}
+	}
+	hook := it.instrument
+	return wrapped, func() {
+		if hook == nil {
+			return
+		}
+		for idx := range stages {
+			hook(OpStats{
+				Stage:    idx,
+				Name:     stages[idx].name,
+				In:       int(stages[idx].in.Load()),
+				Out:      int(stages[idx].out.Load()),
+				Duration: time.Duration(stages[idx].nanos.Load()),
+			})
+		}
+	}
+}
+
+// instrumentOperation returns the given operation, recording each element it's applied to in s.
+func instrumentOperation[T any](s *stage, op func(*maybe[T])) func(*maybe[T]) {
+	return func(m *maybe[T]) {
+		before := m.val
+		start := time.Now()
+		op(m)
+		s.nanos.Add(int64(time.Since(start)))
+		s.in.Add(1)
+		if m.ok {
+			s.out.Add(1)
+		}
+		if s.tracer == nil {
+			return
+		}
+		switch {
+		case m.err != nil:
+			s.tracer.printf("%s: %v failed: %v", s.label(), before, m.err)
+		case !m.ok:
+			s.tracer.printf("%s: %v filtered out", s.label(), before)
+		default:
+			s.tracer.printf("%s: %v -> %v", s.label(), before, m.val)
+		}
+	}
+}
+
+// instrumentBarrier returns the given barrier function, recording the elements it receives and returns in s.
+func instrumentBarrier[T any](s *stage, fn func([]T) []T) func([]T) []T {
+	return func(vals []T) []T {
+		in := len(vals)
+		start := time.Now()
+		vals = fn(vals)
+		s.nanos.Add(int64(time.Since(start)))
+		s.in.Add(int64(in))
+		s.out.Add(int64(len(vals)))
+		if s.tracer != nil {
+			s.tracer.printf("%s: %d values in, %d out", s.label(), in, len(vals))
+		}
+		return vals
+	}
+}
+
+// progress makes the iterator report the number of elements read from the source to fn, every time another every elements
+// have been read and whenever the source is found to be exhausted after reading more of it. The count is restarted by
+// Reset, which holds the same lock as the read function when the ThreadSafe option is used.
+func (it *iter[T]) progress(every int, fn func(processed, total int)) {
+	every = maxInt(every, 1)
+	read := it.readFunc
+	processed, finished := 0, false
+	next := it.resetFunc
+	it.resetFunc = func() {
+		processed, finished = 0, false
+		if next != nil {
+			next()
+		}
+	}
+	it.readFunc = func(it *iter[T]) (T, bool) {
+		val, ok := read(it)
+		total := -1
+		if it.sized {
+			total = it.size
+		}
+		switch {
+		case ok:
+			processed, finished = processed+1, false
+			if processed%every == 0 {
+				fn(processed, total)
+			}
+		case !finished:
+			finished = true
+			if processed%every != 0 || processed == 0 {
+				fn(processed, total)
+			}
+		}
+		return val, ok
+	}
+	if !it.threadSafe { // otherwise nextFunc is synchronizedNext, which calls readFunc
+		it.nextFunc = it.readFunc
+	}
+}
@@ -0,0 +1,207 @@
+package iterator_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Instrument(t *testing.T) {
+	isEven := func(val int) bool {
+		return val%2 == 0
+	}
+	double := func(val int) int {
+		return val * 2
+	}
+	descending := func(a, b int) bool {
+		return a > b
+	}
+	tests := map[string]struct {
+		opts     []iterator.FromOption
+		collect  func(iterator.Of[int]) []int
+		expected []iterator.OpStats
+	}{
+		"collect": {
+			collect: iterator.Of[int].Collect,
+			expected: []iterator.OpStats{
+				{Stage: 0, In: 10, Out: 5},
+				{Stage: 1, In: 5, Out: 5},
+				{Stage: 2, In: 5, Out: 5},
+				{Stage: 3, In: 5, Out: 2},
+			},
+		},
+		"parallel": {
+			opts:    []iterator.FromOption{iterator.Parallel(4)},
+			collect: iterator.Of[int].Collect,
+			expected: []iterator.OpStats{
+				{Stage: 0, In: 10, Out: 5},
+				{Stage: 1, In: 5, Out: 5},
+				{Stage: 2, In: 5, Out: 5},
+				{Stage: 3, In: 5, Out: 2},
+			},
+		},
+		"seq": {
+			collect: func(it iterator.Of[int]) []int {
+				var result []int
+				for val := range it.Seq() {
+					result = append(result, val)
+				}
+				return result
+			},
+			expected: []iterator.OpStats{
+				{Stage: 0, In: 10, Out: 5},
+				{Stage: 1, In: 5, Out: 5},
+				{Stage: 2, In: 5, Out: 5},
+				{Stage: 3, In: 5, Out: 2},
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stats []iterator.OpStats
+			opts := append(test.opts, iterator.Instrument(func(s iterator.OpStats) {
+				s.Duration = 0 // durations vary between runs
+				stats = append(stats, s)
+			}))
+			it := iterator.Range(0, 10, 1, opts...).
+				Filter(isEven).
+				Map(double).
+				Sort(descending).
+				Filter(func(val int) bool {
+					return val > 10
+				})
+			if result := test.collect(it); !reflect.DeepEqual(result, []int{16, 12}) {
+				t.Errorf("expected [16 12], got %v", result)
+			}
+			if !reflect.DeepEqual(stats, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, stats)
+			}
+		})
+	}
+}
+
+func Test_Trace(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var stats []iterator.OpStats
+	it := iterator.From([]int{1, 2, 3, 4}, iterator.Trace(buf), iterator.Instrument(func(s iterator.OpStats) {
+		stats = append(stats, s)
+	})).Apply(
+		iterator.Named("evens", iterator.FilterOp(func(val int) bool {
+			return val%2 == 0
+		})),
+		iterator.Named("halve", iterator.TryMapOp(func(val int) (int, error) {
+			if val == 4 {
+				return 0, errors.New("too big")
+			}
+			return val / 2, nil
+		})),
+	).Sort(func(a, b int) bool {
+		return a < b
+	})
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{1}) {
+		t.Errorf("expected [1], got %v", result)
+	}
+	expected := `stage 0 (evens): 1 filtered out
+stage 0 (evens): 2 -> 2
+stage 1 (halve): 2 -> 1
+stage 0 (evens): 3 filtered out
+stage 0 (evens): 4 -> 4
+stage 1 (halve): 4 failed: too big
+stage 2: 1 values in, 1 out
+`
+	if buf.String() != expected {
+		t.Errorf("expected trace:\n%s\ngot:\n%s", expected, buf.String())
+	}
+	names := []string{}
+	for _, s := range stats {
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"evens", "halve", ""}) {
+		t.Errorf("expected the stages to be named [evens halve ], got %q", names)
+	}
+}
+
+func Test_OnProgress(t *testing.T) {
+	type call struct {
+		processed, total int
+	}
+	tests := map[string]struct {
+		it       func(opt iterator.FromOption) iterator.Of[int]
+		expected []call
+	}{
+		"slice": {
+			it: func(opt iterator.FromOption) iterator.Of[int] {
+				return iterator.From([]int{1, 2, 3, 4, 5, 6, 7}, opt)
+			},
+			expected: []call{{3, 7}, {6, 7}, {7, 7}},
+		},
+		"unknown length": {
+			it: func(opt iterator.FromOption) iterator.Of[int] {
+				n := 0
+				return iterator.FromFunc(func() (int, bool) {
+					n++
+					return n, n <= 6
+				}, opt)
+			},
+			expected: []call{{3, -1}, {6, -1}},
+		},
+		"empty": {
+			it: func(opt iterator.FromOption) iterator.Of[int] {
+				return iterator.From([]int{}, opt)
+			},
+			expected: []call{{0, 0}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls []call
+			it := test.it(iterator.OnProgress(3, func(processed, total int) {
+				calls = append(calls, call{processed, total})
+			})).Filter(func(val int) bool {
+				return val%2 == 0
+			})
+			it.Collect()
+			it.Collect() // an exhausted source isn't reported again
+			if !reflect.DeepEqual(calls, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, calls)
+			}
+		})
+	}
+}
+
+func Test_OnProgress_Reset(t *testing.T) {
+	type call struct {
+		processed, total int
+	}
+	tests := map[string]func(opts ...iterator.FromOption) iterator.Of[int]{
+		"slice": func(opts ...iterator.FromOption) iterator.Of[int] {
+			return iterator.From([]int{1, 2, 3, 4}, opts...)
+		},
+		"source": func(opts ...iterator.FromOption) iterator.Of[int] {
+			return iterator.FromSource[int](&countdown{from: 4, next: 4, rewind: true}, opts...)
+		},
+		"thread_safe_source": func(opts ...iterator.FromOption) iterator.Of[int] {
+			return iterator.FromSource[int](&countdown{from: 4, next: 4, rewind: true}, append(opts, iterator.ThreadSafe(true))...)
+		},
+	}
+	for name, newIt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var calls []call
+			it := newIt(iterator.OnProgress(2, func(processed, total int) {
+				calls = append(calls, call{processed, total})
+			}))
+			it.Collect()
+			it.Reset()
+			if result := it.Collect(); len(result) != 4 {
+				t.Errorf("expected the source to be rewound, got %v", result)
+			}
+			expected := []call{{2, 4}, {4, 4}, {2, 4}, {4, 4}}
+			if !reflect.DeepEqual(calls, expected) {
+				t.Errorf("expected %+v, got %+v", expected, calls)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package iterator
 
+import goiter "iter"
+
 // Of provides a high-level interface for iterating over a slice.
 type Of[T any] interface {
 	// Next returns the next value in the iterator, consuming it in the process, as well as a boolean indicating whether
@@ -7,43 +9,201 @@ type Of[T any] interface {
 	Next() (T, bool)
 	// ForEach iterates over the iterator, calling the given function for each value and consuming the iterator.
 	ForEach(fn func(T))
+	// ForEachUntil is like ForEach, but stops as soon as the given function returns false, leaving the rest of the iterator
+	// unconsumed, so searches don't need a loop calling Next.
+	ForEachUntil(fn func(T) bool)
+	// ForEachBatch applies all of the chained operations to the iterator and calls the given function with the values left,
+	// n at a time, so they can be handled in bulk, such as by a single database insert. The last batch holds whatever is
+	// left, and may be shorter. The same slice is reused for every batch, so fn must copy any values it keeps after
+	// returning. ForEachBatch panics if n is less than 1.
+	ForEachBatch(n int, fn func([]T))
+	// TryForEach is like ForEach, but the given function can fail. Iteration stops at the first error, which is returned.
+	// If the source itself fails, such as FromGlob, its error is returned once the values before it have been passed to fn.
+	TryForEach(fn func(T) error) error
 	// Map returns a new iterator that applies the given function to each value in the iterator. The function
 	// is lazily evaluated, so it is not applied until the iterator is collected.
 	Map(fn func(T) T) Of[T]
 	// Filter returns a new iterator that keeps only the values in the iterator that return true when passed to the given
 	// function. The function is lazily evaluated, so it is not applied until the iterator is collected.
 	Filter(fn func(T) bool) Of[T]
+	// Compact returns a new iterator that drops the zero values in the iterator, such as nil pointers, nil slices, empty
+	// strings, and structs whose fields are all zero. The check is lazily evaluated, so it is not applied until the iterator
+	// is collected.
+	Compact() Of[T]
+	// MapConcurrent is like Map, but calls the given function for up to concurrency values at a time, which suits I/O-bound
+	// functions such as a request per value. The results are kept in the order of their values, however long each call
+	// takes. If concurrency is less than 1, runtime.GOMAXPROCS(0) is used. Like Sort, MapConcurrent buffers every value that
+	// survived the operations chained before it, so the operations chained after it are applied once all the calls have
+	// returned. The function must be safe for concurrent use, and is lazily evaluated, so it is not applied until the
+	// iterator is collected.
+	MapConcurrent(fn func(T) T, concurrency int) Of[T]
+	// TryMap is like Map, but the given function can fail. If it returns an error, the value is dropped and the error is
+	// reported by TryCollect, which stops at the first error. Other terminal operations, such as Collect, silently drop the
+	// values that failed. The function is lazily evaluated, so it is not applied until the iterator is collected.
+	TryMap(fn func(T) (T, error)) Of[T]
+	// TryFilter is like Filter, but the given function can fail. Errors are handled the same way as for TryMap. The function
+	// is lazily evaluated, so it is not applied until the iterator is collected.
+	TryFilter(fn func(T) (bool, error)) Of[T]
 	// Unique returns a new iterator that filters out duplicate values in the iterator. The function is
 	// lazily evaluated, so it is not applied until the iterator is collected. This is a convenience method that is equivalent
-	// to calling Filter with a function that keeps track of the values it has seen. If the iterator contains pointers, the
-	// DerefPointers option can be used to dereference the pointers before evaluating uniqueness.
+	// to calling Filter with a function that keeps track of the values it has seen. The WithKeyFunc option evaluates
+	// uniqueness on a key derived from each value instead. If the iterator contains pointers, the DerefPointers option can
+	// be used to dereference the pointers before evaluating uniqueness. With the KeepLast option, the last occurrence of
+	// each value is kept instead of the first, which means buffering every value, as Sort does.
 	Unique(opts ...UniqueOption) Of[T]
+	// Sort returns a new iterator that sorts the values that survived the operations chained before it, using the given
+	// function to determine whether a should come before b. The sort is stable. Unlike Map and Filter, Sort has to buffer
+	// every value before the operations chained after it can run, so they are applied to the sorted values. The function is
+	// lazily evaluated, so it is not applied until the iterator is collected.
+	Sort(less func(a, b T) bool) Of[T]
+	// TakeLast returns a new iterator that keeps only the last n values that survived the operations chained before it. Like
+	// Sort, TakeLast needs to see every value before the operations chained after it can run, but Collect and the terminals
+	// built on it only hold about twice n values at a time while doing so. The parallel options still buffer the whole
+	// source. If n is less than 1, every value is dropped.
+	TakeLast(n int) Of[T]
+	// SkipLast returns a new iterator that drops the last n values that survived the operations chained before it. Values are
+	// streamed as usual, each one held back until n more have been seen, so at most n values are buffered. The held values
+	// are cleared by Reset, so a rewound source has its own last n values dropped. If n is less than 1, nothing is dropped.
+	SkipLast(n int) Of[T]
+	// Shuffle returns a new iterator that randomizes the order of the values that survived the operations chained before it.
+	// Like Sort, Shuffle has to buffer every value, so the operations chained after it are applied to the shuffled values. By
+	// default the source passed to From using the WithRand option is used, or the math/rand package's global source if there
+	// isn't one; the ShuffleRand and ShuffleSeed options override it for this operation. The function is lazily evaluated,
+	// so it is not applied until the iterator is collected.
+	Shuffle(opts ...ShuffleOption) Of[T]
+	// Apply chains the given operations to the iterator in order, as if each of the corresponding methods had been called,
+	// so a pipeline defined once as a slice of Operation values can be reused across many iterators. Operations that keep
+	// state between elements, such as UniqueOp, keep separate state for each iterator they are applied to.
+	Apply(ops ...Operation[T]) Of[T]
+	// Clone returns an independent copy of the iterator, at the same position and with the same chained operations, so a
+	// configured pipeline can be branched into different operations or terminals without declaring it again. Operations
+	// chained to one of them afterwards don't affect the other, and operations that keep state between elements, such as
+	// Unique, start afresh in the copy. Slices and ranges are read independently, but sources that can't be rewound, such
+	// as FromFunc and FromChannel, are shared, so each of their elements is only returned by whichever iterator reads it
+	// first. The functions passed to the chained operations are shared too, as is the source of randomness set by WithRand.
+	// Stopping either copy releases the shared source for both, and the functions registered using OnStop are only called
+	// once, however many copies are stopped.
+	Clone() Of[T]
 	// Collect applies all of the chained map and filter operations to the iterator and returns the resulting slice.
 	Collect() []T
+	// TryCollect is like Collect, but stops as soon as one of the chained TryMap or TryFilter functions returns an error,
+	// returning that error and no values. The same goes for sources that can fail, such as FromGlob. If the pipeline
+	// contains an operation that buffers every value, such as Sort, every value before it is processed first, so errors
+	// from the operations chained after it are only reported once the whole source has been consumed. TryCollect always
+	// runs on a single goroutine, even if a parallel option is used.
+	TryCollect() ([]T, error)
+	// CollectInto is like Collect, but appends the resulting values to dst and returns the extended slice, as the built-in
+	// append does. Passing a slice with enough spare capacity, such as one returned by an earlier call resliced to zero
+	// length, lets a buffer be reused across repeated collections instead of allocating a new one each time.
+	CollectInto(dst []T) []T
+	// CollectN is like Collect, but stops once n values have survived the chained operations, leaving the rest of the source
+	// unread, so finding the first few matches in a large source doesn't process all of it. Fewer values are returned if
+	// the source runs out first. If the pipeline contains an operation that needs every value at once, such as Sort, the
+	// whole source is processed before the first n values are taken. A parallel option has no effect.
+	CollectN(n int) []T
+	// Sample applies all of the chained operations to the iterator and returns n values chosen uniformly at random from the
+	// values that survived them, in no particular order. Reservoir sampling is used, so only n values are held in memory at a
+	// time unless the pipeline contains an operation that buffers every value, such as Sort. If fewer than n values survive,
+	// all of them are returned. The source passed to From using the WithRand option is used if there is one.
+	Sample(n int) []T
+	// Drain applies all of the chained operations to the rest of the iterator for their side effects, such as those of the
+	// functions passed to Map or TryMap, and returns the number of values that survived them. Unlike Collect, the values
+	// aren't kept, so no slice is allocated for them unless the pipeline contains an operation that buffers every value,
+	// such as Sort.
+	Drain() int
+	// IsEmpty applies all of the chained operations to the iterator and reports whether no value survived them. It stops at
+	// the first value that does, which is consumed.
+	IsEmpty() bool
+	// Single applies all of the chained operations to the iterator and returns the only value that survived them. If none
+	// did, ErrNoValues is returned, and if more than one did, ErrMultipleValues is returned as soon as the second is found,
+	// leaving the rest of the source unread. Errors from TryMap, TryFilter, and sources that can fail are returned too.
+	Single() (T, error)
+	// TopK applies all of the chained operations to the iterator and returns the k greatest values according to the given
+	// less function, greatest first. Only k values are held in memory at a time, in a heap, so this is much cheaper than
+	// sorting every value to keep the first few, unless the pipeline contains an operation that buffers every value, such as
+	// Sort. If fewer than k values survive, all of them are returned. Equal values may be returned in any order.
+	TopK(k int, less func(a, b T) bool) []T
+	// BottomK is like TopK, but returns the k least values according to the given less function, least first.
+	BottomK(k int, less func(a, b T) bool) []T
+	// Seq returns a range-over-func sequence of the values in the iterator, so it can be used in a for loop or passed to
+	// functions from the standard library that accept an iter.Seq. Like Collect, this applies the chained operations, but
+	// values are yielded one at a time as they are produced, and breaking out of the loop stops the iteration without
+	// processing the remaining values.
+	Seq() goiter.Seq[T]
+	// Seq2 is like Seq, but yields each value along with its index in the output, counting from zero. Values removed by the
+	// chained operations don't count, so the indexes are the same as in the slice returned by Collect.
+	Seq2() goiter.Seq2[int, T]
 	// Channel returns a channel that will be populated with the values in the iterator. The channel will be closed when
 	// there are no more values, indicating that the iterator has been consumed. This is not the same as collecting, as
 	// this does not apply the chained map and filter operations to each element. If you want a channel that applies the
-	// chained map and filter operations, use CollectChannel.
-	Channel() <-chan T
+	// chained map and filter operations, use CollectChannel. By default the channel's buffer can hold the whole source; use
+	// the ChannelBuffer option to make the sending goroutine wait for a receiver that falls behind, and the WithContext
+	// option to stop it if the channel might not be read until the iterator is exhausted. The channel is always closed.
+	Channel(opts ...IntoChannelOption) <-chan T
 	// IntoChannel populates the given channel with the values in the iterator. If shouldClose is true, the channel will be
 	// closed when there are no more values, indicating that the iterator has been consumed. This is not the same as
 	// collecting, as this does not apply the chained map and filter operations to each element. If you want the channel to
-	// be populated with the values after applying the chained map and filter operations, use CollectIntoChannel.
+	// be populated with the values after applying the chained map and filter operations, use CollectIntoChannel. Use the
+	// WithContext option to stop sending if the channel might not be read until the iterator is exhausted.
 	IntoChannel(ch chan<- T, opts ...IntoChannelOption)
 	// CollectChannel returns a channel that will be populated with the values in the iterator. The channel will be closed when
 	// there are no more values, indicating that the iterator has been consumed. This method does apply the chained map and
-	// filter operations, so it is equivalent to calling Collect and then sending the resulting slice to a channel.
-	CollectChannel() <-chan T
+	// filter operations, streaming each value as soon as it has been processed. See CollectIntoChannel for details. The
+	// options are the same as for Channel.
+	CollectChannel(opts ...IntoChannelOption) <-chan T
 	// CollectIntoChannel populates the given channel with the values in the iterator. If shouldClose is true, the channel will be
 	// closed when there are no more values, indicating that the iterator has been consumed. This method does apply the chained
-	// map and filter operations, so it is equivalent to calling Collect and then sending the resulting slice to a channel.
+	// map and filter operations. Each value is sent as soon as it has been processed, so receivers see the first results
+	// straight away and only one value is held at a time, unless the pipeline contains an operation that needs every value
+	// at once, such as Sort, in which case the values are collected first. The operations are applied on the sending
+	// goroutine, so the parallel options have no effect here.
 	CollectIntoChannel(ch chan<- T, opts ...IntoChannelOption)
+	// Broadcast is like CollectIntoChannel, but sends every value to each of the given channels, such as to feed both a
+	// metrics consumer and a persistence consumer from the same pipeline. Each value is sent to the channels in the order
+	// they are given before the next value is processed, so a receiver that falls behind holds up the others once its
+	// channel's buffer is full; use the WithContext option if any of them might stop reading early. Each channel receives
+	// its own copy of the value, although a pointer or a slice still refers to the same data. If the CloseChannel option is
+	// used, every channel is closed once the iterator has been consumed.
+	Broadcast(chs []chan<- T, opts ...IntoChannelOption)
+	// FanOut returns n channels that are populated with the values in the iterator, dealt out round-robin, such as to feed
+	// a pool of workers with a channel each. If n is less than 1, runtime.GOMAXPROCS(0) channels are returned. Like
+	// CollectChannel, this applies the chained operations, streaming each value as soon as it has been processed, and all
+	// of the channels are closed once the iterator has been consumed. Values are sent in order by a single goroutine, so a
+	// receiver that stops reading holds up all of the others once its channel's buffer is full; use the WithContext option
+	// if any of them might stop early. The PartitionKey option sends all of the values with the same key to the same
+	// channel instead, and the ChannelBuffer option sets the buffer size of each channel.
+	FanOut(n int, opts ...IntoChannelOption) []<-chan T
 	// Reduce applies the given function to each value in the iterator, passing the result of the previous function call as the
 	// first argument and the next value as the second argument until there are no more values. The initial value is passed to
 	// the anonymous function as the first argument on the first iteration.
 	Reduce(fn func(accumulator, next T) T, initial T) T
+	// Stop ends the iteration early, releasing the source: every later call to Next returns false, and sources that hold
+	// resources while suspended, such as FromSeq and FromGlob, release them. Functions registered using the OnStop option
+	// are called, so producers feeding a FromFunc or FromChannel source can be told to stop. Call Stop, typically deferred,
+	// when abandoning an iterator over a generator before it's exhausted, such as after breaking out of a loop over Seq.
+	// Stopping an iterator that holds nothing, such as one over a slice, only ends the iteration. Calling Stop more than
+	// once has no further effect.
+	Stop()
+	// Close stops the iteration the way Stop does, then returns the error from releasing the source, if any, such as the one
+	// returned when closing the rows of FromRows, so that an iterator can be used as an io.Closer. Releasing a source that
+	// holds nothing can't fail, so Close returns nil for it. With the Prefetch option, the source is only released once
+	// reading ahead has stopped, which may be after Close has returned nil.
+	Close() error
+	// Len returns the number of elements a collection would return from this point, and whether that number is known. It's
+	// known for sources of known length, such as slices, ranges, and sources passed to FromSource that report their length,
+	// as long as none of the chained operations can add or remove elements: Map, MapConcurrent, Sort, and Shuffle keep it
+	// known, while Filter and the other operations don't.
+	Len() (int, bool)
+	// SizeHint returns an upper bound on the number of elements left in the source, ignoring the chained operations, or 0 if
+	// the length of the source isn't known. It's what Collect and Channel use to size the slices and channels they allocate.
+	SizeHint() int
+	// State returns the position of the iterator in its source, which can be persisted, such as after each batch passed to
+	// ForEachBatch, and passed to Resume to carry on from there, such as after a crash. The position counts the elements
+	// read from the source, whether or not they survived the chained operations.
+	State() IterState
 	// Reset resets the iterator to the beginning of the source slice. This is useful if you want to iterate over the same
-	// slice multiple times. Note that this does not reset the chained map and filter operations. If you want to reset those,
-	// you should create a new iterator using the From function.
-	Reset()
+	// slice multiple times. Note that by default this does not reset the chained map and filter operations. If you want to
+	// reset those too, use the ClearOperations option. Sources that can't be rewound, such as the function passed to
+	// FromFunc, keep returning elements from where they left off.
+	Reset(opts ...ResetOption)
 }
@@ -8,46 +8,297 @@
 package iterator
 
 import (
+	"container/heap"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"math/rand"
 	"reflect"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type maybe[T any] struct {
-	ok  bool // whether the current element should be included in the result
-	val T    // the value of the current element
+	ok  bool  // whether the current element should be included in the result
+	val T     // the value of the current element
+	err error // the error returned by a Try operation for the current element, in which case ok is false
+}
+
+// reset prepares the maybe for processing the given value.
+func (m *maybe[T]) reset(val T) {
+	m.ok = true
+	m.val = val
+	m.err = nil
 }
 
 type iter[T any] struct {
-	mu         sync.Mutex               // mutex to synchronize access to the iterator when the ThreadSafe option is used
-	nextFunc   func(*iter[T]) (T, bool) // the function to be used when calling the Next method. This is set to next or synchronizedNext depending on the options used when creating the iterator.
-	nextIndex  int                      // the index of the next element to be returned by the Next method
-	source     []T                      // the source slice. Could be the original slice or a copy, depending on the options used when creating the iterator.
-	operations []func(*maybe[T])        // the operations to be performed on each element of the source slice
+	mu          sync.Mutex               // mutex to synchronize access to the iterator when the ThreadSafe option is used
+	nextFunc    func(*iter[T]) (T, bool) // the function to be used when calling the Next method. This is set to readFunc or synchronizedNext depending on the options used when creating the iterator.
+	readFunc    func(*iter[T]) (T, bool) // the function that reads the element at nextIndex from the source without any synchronization, such as next for slices
+	collectFunc func(*iter[T], []T) []T  // the function that applies the operations and appends the results to the given slice, used by Collect and CollectInto. This is set to collect unless a parallel execution option is used.
+	nextIndex   int                      // the index of the next element to be returned by the Next method
+	size        int                      // the number of elements in the source, used to pre-allocate buffers
+	sized       bool                     // whether size is exactly the number of elements in the source, rather than 0 for a source of unknown length
+	yield       float64                  // the expected fraction of the source left after the chained operations, set by the Yield option, or 0 if unknown
+	source      []T                      // the source slice. Could be the original slice or a copy, depending on the options used when creating the iterator.
+	pipe        *pipeline[T]             // the operations chained to the iterator
+	rand        *rand.Rand               // the source of randomness used by random operations such as Shuffle and Sample. The global source is used if nil.
+	err         error                    // the error that ended the source early, reported by TryCollect and TryForEach. Only sources that can fail, such as FromGlob, set it.
+	threadSafe  bool                     // whether the ThreadSafe option is used, meaning nextFunc is synchronizedNext
+	stopFunc    func()                   // the function that releases the source when Stop is called, or nil if there's nothing to release
+	resetFunc   func()                   // the function that rewinds the source when Reset is called, or nil if only nextIndex needs resetting
+	closeErr    func() error             // the function returning the error from releasing the source, reported by Close, or nil if releasing it can't fail
+	instrument  func(OpStats)            // the function the statistics of each operation are reported to after a terminal operation, set by the Instrument option
+	trace       io.Writer                // where each element's journey through the operations is written during a terminal operation, set by the Trace option
+}
+
+// pipeline holds the operations chained to an iterator. When the ThreadSafe option is used, a pipeline is never changed
+// once it's been stored in an iterator. Chaining an operation stores a changed copy instead, so that collections already
+// running on other goroutines keep using the operations they started with.
+type pipeline[T any] struct {
+	operations []func(*maybe[T])              // the operations to be performed on each element of the source slice
+	barriers   []barrier[T]                   // the operations that need every element that survived the preceding operations before they can run, such as Sort
+	sequential bool                           // whether any of the operations keeps state between elements, meaning they can't be applied concurrently. Barriers don't count, as they always run after the concurrent part of a collection.
+	stateful   map[int]func() func(*maybe[T]) // the constructors of the operations that keep state between elements, such as Unique, by index, so that Clone can give the copy its own state
+	preserving int                            // the number of operations and barriers that never change the number of elements, such as Map and Sort
+	names      map[int]string                 // the names given to operations and barriers using Named, by their position in the pipeline
+	rewound    []int                          // the indexes of the stateful operations whose state only applies to one pass over the source, such as SkipLast, so that Reset can clear it
+}
+
+// clone returns a copy of the pipeline that can be changed without affecting the original.
+func (p *pipeline[T]) clone() *pipeline[T] {
+	return &pipeline[T]{
+		operations: slices.Clone(p.operations),
+		barriers:   slices.Clone(p.barriers),
+		sequential: p.sequential,
+		stateful:   maps.Clone(p.stateful),
+		preserving: p.preserving,
+		names:      maps.Clone(p.names),
+		rewound:    slices.Clone(p.rewound),
+	}
+}
+
+// stages returns the number of operations and barriers in the pipeline, which is the position the next one will take.
+func (p *pipeline[T]) stages() int {
+	return len(p.operations) + len(p.barriers)
+}
+
+// preservesLen returns whether none of the operations can change the number of elements, so the number of elements
+// collected is the number left in the source.
+func (p *pipeline[T]) preservesLen() bool {
+	return p.preserving == p.stages()
+}
+
+// addBarrier chains a barrier running the given function after the operations already chained.
+func (p *pipeline[T]) addBarrier(fn func([]T) []T) {
+	p.barriers = append(p.barriers, barrier[T]{after: len(p.operations), fn: fn})
+}
+
+// addStateful chains an operation that keeps state between elements, created by newOp, which Clone calls again to give
+// the copy its own state. The pipeline becomes sequential, as the state is shared between elements.
+func (p *pipeline[T]) addStateful(newOp func() func(*maybe[T])) {
+	if p.stateful == nil {
+		p.stateful = make(map[int]func() func(*maybe[T]))
+	}
+	p.stateful[len(p.operations)] = newOp
+	p.sequential = true
+	p.operations = append(p.operations, newOp())
+}
+
+// addPerPass is like addStateful, but the operation's state only applies to one pass over the source, so Reset replaces
+// the operation with a new one created by newOp.
+func (p *pipeline[T]) addPerPass(newOp func() func(*maybe[T])) {
+	p.rewound = append(p.rewound, len(p.operations))
+	p.addStateful(newOp)
+}
+
+// chain applies the given change to the iterator's pipeline, returning the iterator. With the ThreadSafe option, the
+// change is made to a copy that then replaces the pipeline.
+func (it *iter[T]) chain(change func(p *pipeline[T])) Of[T] {
+	it.mu.Lock() // uncontended unless the ThreadSafe option is used
+	defer it.mu.Unlock()
+	if !it.threadSafe {
+		change(it.pipe)
+		return it
+	}
+	p := it.pipe.clone()
+	change(p)
+	it.pipe = p
+	return it
+}
+
+// pipeline returns the operations currently chained to the iterator, which a terminal operation should use throughout.
+func (it *iter[T]) pipeline() *pipeline[T] {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.pipe
 }
 
 // From returns a new iterator for the given source. There are several options that can be used to configure the
 // behavior of the iterator. See the documentation for the FromOption type for more information.
 func From[T any](source []T, opts ...FromOption) Of[T] {
+	it := newIter(source, next[T], len(source), opts)
+	it.sized = true
+	return it
+}
+
+// IterState is the position of an iterator in its source, as returned by the State method. It can be encoded as JSON to be
+// persisted between runs.
+type IterState struct {
+	Index int `json:"index"` // the number of elements read from the source
+}
+
+// Resume returns a new iterator for the given source, like From, starting from the position recorded in the given state
+// rather than the beginning, so a long-running job over the same slice can carry on where an earlier run left off. Reset
+// rewinds the iterator to the beginning of the source, not to the saved position. If the source is shorter than the
+// position, the iterator is empty.
+func Resume[T any](source []T, state IterState, opts ...FromOption) Of[T] {
+	it := From(source, opts...).(*iter[T])
+	it.nextIndex = minInt(maxInt(state.Index, 0), len(source))
+	return it
+}
+
+// newIter returns a new iterator that reads its elements using the given function, configured with the given options. The
+// source slice is only used by iterators reading from a slice, and can be nil otherwise.
+func newIter[T any](source []T, readFunc func(*iter[T]) (T, bool), size int, opts []FromOption) *iter[T] {
 	it := &iter[T]{
-		source: source,
+		source:   source,
+		readFunc: readFunc,
+		size:     size,
 	}
 	options := new(fromOptions)
 	options.bufferLen = 64
 	for _, opt := range opts {
 		opt(options)
 	}
-	it.nextFunc = next[T]
+	it.rand = options.rand
+	if options.yield > 0 && options.yield <= 1 {
+		it.yield = options.yield
+	}
+	it.nextFunc = readFunc
+	it.collectFunc = collect[T]
 	if options.copySource {
 		it.source = make([]T, len(source))
 		copy(it.source, source)
 	}
 	if options.threadSafe {
 		it.nextFunc = synchronizedNext[T]
+		it.threadSafe = true
 	}
-	it.operations = make([]func(*maybe[T]), 0, options.bufferLen)
+	it.stopFunc = options.onStop
+	it.instrument = options.instrument
+	it.trace = options.trace
+	if options.prefetch > 0 && source == nil && size == 0 { // slices and ranges have nothing to wait for, and can be rewound
+		it.prefetch(options.prefetch)
+	}
+	if options.onProgress != nil {
+		it.progress(options.progressEvery, options.onProgress)
+	}
+	if options.parallel {
+		workers, chunkSize, workStealing := options.workers, options.chunkSize, options.workStealing
+		it.collectFunc = func(it *iter[T], dst []T) []T {
+			return chunkedCollect(it, dst, workers, chunkSize, workStealing)
+		}
+	}
+	if options.collectFunc != nil {
+		collectFunc, ok := options.collectFunc.(func(*iter[T], []T) []T)
+		if !ok {
+			panic(fmt.Sprintf("iterator: the PartitionedParallel key function doesn't accept the %s elements of the source", reflect.TypeOf((*T)(nil)).Elem()))
+		}
+		it.collectFunc = collectFunc
+	}
+	it.pipe = &pipeline[T]{operations: make([]func(*maybe[T]), 0, options.bufferLen)}
 	return it
 }
 
+// prefetch makes the iterator read up to n elements ahead of its consumer, on a goroutine started by the first read. The
+// goroutine reads using a private iterator, so the source's bookkeeping, such as nextIndex, is never shared between
+// goroutines, and hands the elements over with the error that ended the source, if any, which the consumer then records.
+// As a source must not be released while it's being read, the functions registered to run on Stop are deferred until the
+// goroutine has stopped reading.
+func (it *iter[T]) prefetch(n int) {
+	read := it.readFunc
+	buffer := make(chan maybe[T], n)
+	done := make(chan struct{})   // closed by Stop
+	exited := make(chan struct{}) // closed once the goroutine has stopped reading
+	var release func()
+	var releaseOnce sync.Once
+	releaseSource := func() {
+		releaseOnce.Do(func() {
+			if release != nil {
+				release()
+			}
+		})
+	}
+	fill := func() {
+		defer close(buffer)
+		reader := new(iter[T])
+		stopped := func() {
+			close(exited)
+			releaseSource()
+		}
+		for {
+			select {
+			case <-done:
+				stopped()
+				return
+			default:
+			}
+			val, ok := read(reader)
+			select {
+			case buffer <- maybe[T]{ok: ok, val: val, err: reader.err}:
+			case <-done:
+				stopped()
+				return
+			}
+			if !ok {
+				close(exited)
+				select {
+				case <-done: // Stop was called after the last read, but may have found the goroutine still running
+					releaseSource()
+				default:
+				}
+				return
+			}
+		}
+	}
+	started := false
+	it.readFunc = func(it *iter[T]) (T, bool) {
+		if !started {
+			started = true
+			release = it.stopFunc
+			it.stopFunc = func() {
+				close(done)
+				select {
+				case <-exited: // the goroutine won't release the source itself
+					releaseSource()
+				default:
+				}
+			}
+			go fill()
+		}
+		mb := <-buffer
+		if !mb.ok {
+			if mb.err != nil {
+				it.err = mb.err
+			}
+			return *new(T), false
+		}
+		it.nextIndex++
+		return mb.val, true
+	}
+	if !it.threadSafe { // otherwise synchronizedNext calls readFunc while holding the lock
+		it.nextFunc = it.readFunc
+	}
+}
+
 func next[T any](it *iter[T]) (T, bool) {
 	if it.nextIndex >= len(it.source) {
 		return *new(T), false
@@ -59,7 +310,7 @@ func next[T any](it *iter[T]) (T, bool) {
 func synchronizedNext[T any](it *iter[T]) (T, bool) {
 	it.mu.Lock()
 	defer it.mu.Unlock()
-	return next(it)
+	return it.readFunc(it)
 }
 
 func (it *iter[T]) Next() (T, bool) {
@@ -77,17 +328,52 @@ func (it *iter[T]) ForEach(fn func(T)) {
 }
 
 func (it *iter[T]) Map(fn func(T) T) Of[T] {
-	it.operations = append(it.operations, func(m *maybe[T]) {
-		m.val = fn(m.val)
+	return it.chain(func(p *pipeline[T]) {
+		p.operations = append(p.operations, func(m *maybe[T]) {
+			m.val = fn(m.val)
+		})
+		p.preserving++
 	})
-	return it
 }
 
 func (it *iter[T]) Filter(fn func(T) bool) Of[T] {
-	it.operations = append(it.operations, func(m *maybe[T]) {
-		m.ok = fn(m.val)
+	return it.chain(func(p *pipeline[T]) {
+		p.operations = append(p.operations, func(m *maybe[T]) {
+			m.ok = fn(m.val)
+		})
+	})
+}
+
+func (it *iter[T]) TryMap(fn func(T) (T, error)) Of[T] {
+	return it.chain(func(p *pipeline[T]) {
+		p.operations = append(p.operations, func(m *maybe[T]) {
+			val, err := fn(m.val)
+			if err != nil {
+				m.ok, m.err = false, err
+				return
+			}
+			m.val = val
+		})
+	})
+}
+
+func (it *iter[T]) TryFilter(fn func(T) (bool, error)) Of[T] {
+	return it.chain(func(p *pipeline[T]) {
+		p.operations = append(p.operations, func(m *maybe[T]) {
+			m.ok, m.err = fn(m.val)
+			if m.err != nil {
+				m.ok = false
+			}
+		})
+	})
+}
+
+func (it *iter[T]) Compact() Of[T] {
+	return it.chain(func(p *pipeline[T]) {
+		p.operations = append(p.operations, func(m *maybe[T]) {
+			m.ok = !reflect.ValueOf(&m.val).Elem().IsZero() // through a pointer, so nil interfaces are handled too
+		})
 	})
-	return it
 }
 
 func (it *iter[T]) Unique(opts ...UniqueOption) Of[T] {
@@ -95,89 +381,763 @@ func (it *iter[T]) Unique(opts ...UniqueOption) Of[T] {
 	for _, opt := range opts {
 		opt(options)
 	}
-	seen := make(map[any]struct{}, len(it.source)) // pre-allocate a map with the same size as the source slice to avoid reallocations
-	filterFn := func(val T) bool {
-		if _, ok := seen[val]; ok {
-			return false
+	key := func(val T) any {
+		return val
+	}
+	if options.keyFunc != nil {
+		keyFunc, ok := options.keyFunc.(func(T) any)
+		if !ok {
+			panic(fmt.Sprintf("iterator: the WithKeyFunc key function doesn't accept the %s elements of the iterator", reflect.TypeOf((*T)(nil)).Elem()))
+		}
+		key = keyFunc
+	} else if options.deref && reflect.TypeOf(*new(T)).Kind() == reflect.Ptr { // if we're dereferencing pointers AND the type of T is a pointer
+		key = func(val T) any { // dereference the pointer before checking for uniqueness
+			return reflect.ValueOf(val).Elem().Interface()
 		}
-		seen[val] = struct{}{}
+	}
+	if options.keepLast {
+		return it.chain(func(p *pipeline[T]) {
+			p.addBarrier(func(vals []T) []T {
+				seen := newSeenSet(options.maxEntries, len(vals))
+				kept := len(vals)
+				for idx := len(vals) - 1; idx >= 0; idx-- { // walk backwards, so the last occurrences are the ones kept
+					if seen.add(key(vals[idx])) {
+						kept--
+						vals[kept] = vals[idx]
+					}
+				}
+				return vals[kept:]
+			})
+		})
+	}
+	newFilter := func() func(*maybe[T]) {
+		seen := newSeenSet(options.maxEntries, it.size)
+		if it.threadSafe { // collections on different goroutines share the seen set
+			mu := new(sync.Mutex)
+			return func(m *maybe[T]) {
+				mu.Lock()
+				defer mu.Unlock()
+				m.ok = seen.add(key(m.val))
+			}
+		}
+		return func(m *maybe[T]) {
+			m.ok = seen.add(key(m.val))
+		}
+	}
+	return it.chain(func(p *pipeline[T]) {
+		p.addStateful(newFilter) // the seen set is shared between elements
+	})
+}
+
+// seenSet is the set of keys Unique has already seen. If it's bounded, only the most recently seen keys are remembered.
+type seenSet struct {
+	keys  map[any]*list.Element // the keys seen, with their position in order if the set is bounded
+	order *list.List            // the keys from the most to the least recently seen, or nil if the set is unbounded
+	limit int                   // the maximum number of keys remembered, or 0 if the set is unbounded
+}
+
+// newSeenSet returns a set remembering up to limit keys, or every key if limit is less than 1. The map is pre-allocated
+// for the given number of keys, or for limit if it's smaller, to avoid reallocations.
+func newSeenSet(limit, size int) *seenSet {
+	if limit < 1 {
+		return &seenSet{keys: make(map[any]*list.Element, size)}
+	}
+	return &seenSet{keys: make(map[any]*list.Element, minInt(limit, size)), order: list.New(), limit: limit}
+}
+
+// add adds the key to the set, returning whether it wasn't already there. If the set is bounded, the key becomes the most
+// recently seen one either way, and the least recently seen key is forgotten if the set is over its limit.
+func (s *seenSet) add(key any) bool {
+	if elem, ok := s.keys[key]; ok {
+		if s.order != nil {
+			s.order.MoveToFront(elem)
+		}
+		return false
+	}
+	if s.order == nil {
+		s.keys[key] = nil
 		return true
 	}
-	if options.deref && reflect.TypeOf(*new(T)).Kind() == reflect.Ptr { // if we're dereferencing pointers AND the type of T is a pointer
-		filterFn = func(val T) bool { // redefine the filterFn to dereference the pointer before checking for uniqueness
-			v := reflect.ValueOf(val).Elem().Interface()
-			if _, ok := seen[v]; ok {
-				return false
+	s.keys[key] = s.order.PushFront(key)
+	if s.order.Len() > s.limit {
+		delete(s.keys, s.order.Remove(s.order.Back()))
+	}
+	return true
+}
+
+// barrier is an operation that buffers the elements that survived the operations chained before it, transforming them all
+// at once. The operations chained after the barrier are applied to its output.
+type barrier[T any] struct {
+	after  int           // the number of operations applied before the barrier
+	fn     func([]T) []T // the transformation applied to the buffered elements
+	window int           // if positive, the barrier only needs the last window elements, so the earlier ones can be discarded while buffering
+}
+
+func (it *iter[T]) TakeLast(n int) Of[T] {
+	if n < 1 {
+		return it.Filter(func(T) bool {
+			return false
+		})
+	}
+	return it.chain(func(p *pipeline[T]) {
+		p.barriers = append(p.barriers, barrier[T]{after: len(p.operations), window: n, fn: func(vals []T) []T {
+			return vals[maxInt(len(vals)-n, 0):]
+		}})
+	})
+}
+
+func (it *iter[T]) SkipLast(n int) Of[T] {
+	if n < 1 {
+		return it
+	}
+	newSkipper := func() func(*maybe[T]) {
+		held := make([]T, 0, n) // the last n values seen, in a ring starting at oldest once it's full
+		oldest := 0
+		skip := func(m *maybe[T]) {
+			if len(held) < n {
+				held = append(held, m.val)
+				m.ok = false
+				return
+			}
+			held[oldest], m.val = m.val, held[oldest] // release the value held back n values ago
+			oldest = (oldest + 1) % n
+		}
+		if it.threadSafe { // collections on different goroutines share the held values
+			mu := new(sync.Mutex)
+			return func(m *maybe[T]) {
+				mu.Lock()
+				defer mu.Unlock()
+				skip(m)
 			}
-			seen[v] = struct{}{}
-			return true
 		}
+		return skip
 	}
-	return it.Filter(filterFn)
+	return it.chain(func(p *pipeline[T]) {
+		p.addPerPass(newSkipper)
+	})
+}
+
+func (it *iter[T]) Sort(less func(a, b T) bool) Of[T] {
+	return it.chain(func(p *pipeline[T]) {
+		p.addBarrier(func(vals []T) []T {
+			sort.SliceStable(vals, func(i, j int) bool {
+				return less(vals[i], vals[j])
+			})
+			return vals
+		})
+		p.preserving++
+	})
+}
+
+func (it *iter[T]) Shuffle(opts ...ShuffleOption) Of[T] {
+	options := new(shuffleOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	shuffle := rand.Shuffle
+	if r := it.randSource(options.rand); r != nil {
+		shuffle = r.Shuffle
+	}
+	return it.chain(func(p *pipeline[T]) {
+		p.addBarrier(func(vals []T) []T {
+			shuffle(len(vals), func(i, j int) {
+				vals[i], vals[j] = vals[j], vals[i]
+			})
+			return vals
+		})
+		p.preserving++
+	})
+}
+
+// randSource returns the source of randomness a random operation should use: the one passed to the operation itself if
+// any, otherwise the one passed to From using the WithRand option. It returns nil if the global source should be used.
+func (it *iter[T]) randSource(override *rand.Rand) *rand.Rand {
+	if override != nil {
+		return override
+	}
+	return it.rand
+}
+
+// SortOrdered is a convenience function that sorts the elements of an iterator of ordered types in ascending order. It is
+// equivalent to calling Sort with a function that compares the elements using the < operator.
+func SortOrdered[T Ordered](it Of[T]) Of[T] {
+	return it.Sort(func(a, b T) bool {
+		return a < b
+	})
+}
+
+// Replace returns a new iterator that replaces the first n values equal to old with new, as strings.Replace does for
+// substrings. If n is negative, every value equal to old is replaced. The replacement is lazily evaluated, so it is not
+// applied until the iterator is collected. As the number of values replaced so far is kept between elements, the
+// iterator is collected sequentially if n isn't negative, even if a parallel option is used.
+func Replace[T comparable](it Of[T], old, new T, n int) Of[T] {
+	if n < 0 {
+		return ReplaceAll(it, old, new)
+	}
+	newReplacer := func() func(T) T {
+		replaced := int64(0) // atomic, as collections on different goroutines share it when the ThreadSafe option is used
+		return func(val T) T {
+			if val == old && atomic.AddInt64(&replaced, 1) <= int64(n) {
+				return new
+			}
+			return val
+		}
+	}
+	impl, ok := it.(*iter[T])
+	if !ok {
+		return it.Map(newReplacer())
+	}
+	return impl.chain(func(p *pipeline[T]) {
+		p.addStateful(func() func(*maybe[T]) {
+			replace := newReplacer()
+			return func(m *maybe[T]) {
+				m.val = replace(m.val)
+			}
+		})
+	})
+}
+
+// ReplaceAll returns a new iterator that replaces every value equal to old with new, as strings.ReplaceAll does for
+// substrings. The replacement is lazily evaluated, so it is not applied until the iterator is collected.
+func ReplaceAll[T comparable](it Of[T], old, new T) Of[T] {
+	return it.Map(func(val T) T {
+		if val == old {
+			return new
+		}
+		return val
+	})
+}
+
+func (it *iter[T]) Len() (int, bool) {
+	it.mu.Lock() // nextIndex is only changed while holding the lock when the ThreadSafe option is used
+	defer it.mu.Unlock()
+	if !it.sized || !it.pipe.preservesLen() {
+		return 0, false
+	}
+	return maxInt(it.size-it.nextIndex, 0), true
+}
+
+func (it *iter[T]) State() IterState {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return IterState{Index: it.nextIndex}
+}
+
+func (it *iter[T]) SizeHint() int {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return maxInt(it.size-it.nextIndex, 0)
+}
+
+// collectCap returns the initial capacity of the slice allocated by Collect and TryCollect: the number of elements left in
+// the source, scaled by the fraction set by the Yield option, if any.
+func (it *iter[T]) collectCap() int {
+	remaining := it.SizeHint()
+	if it.yield > 0 {
+		return int(math.Ceil(float64(remaining) * it.yield))
+	}
+	return remaining
 }
 
 func (it *iter[T]) Collect() []T {
-	result := make([]T, 0, len(it.source))
-	mb := new(maybe[T]) // create a single maybe object to be reused for each iteration, preventing unnecessary allocations
-	it.ForEach(func(val T) {
-		mb.val = val
-		mb.ok = true
-		for _, op := range it.operations {
-			op(mb)
-			if !mb.ok {
-				break
+	return it.collectFunc(it, make([]T, 0, it.collectCap()))
+}
+
+func (it *iter[T]) CollectInto(dst []T) []T {
+	return it.collectFunc(it, dst)
+}
+
+// apply runs the chained operations that come before the first barrier on the given element, stopping at the first
+// operation that filters it out.
+func (p *pipeline[T]) apply(mb *maybe[T]) {
+	ops := p.operations
+	if len(p.barriers) > 0 {
+		ops = ops[:p.barriers[0].after]
+	}
+	applyOps(ops, mb)
+}
+
+func applyOps[T any](ops []func(*maybe[T]), mb *maybe[T]) {
+	for _, op := range ops {
+		op(mb)
+		if !mb.ok {
+			return
+		}
+	}
+}
+
+// flush runs each barrier on the elements of dst from the given index onwards, which survived the operations chained
+// before it, then applies the operations chained after the barrier to its output. The elements before the index are left
+// untouched, as they were already in dst before collecting. Elements for which an operation returned an error are dropped,
+// and the first such error is returned along with the result.
+func (p *pipeline[T]) flush(dst []T, from int) ([]T, error) {
+	if len(p.barriers) == 0 {
+		return dst, nil
+	}
+	var firstErr error
+	vals := dst[from:]
+	mb := new(maybe[T])
+	for i, b := range p.barriers {
+		end := len(p.operations)
+		if i+1 < len(p.barriers) {
+			end = p.barriers[i+1].after
+		}
+		vals = b.fn(vals)
+		kept := vals[:0] // filter in place, the barrier output is owned by the collection
+		for _, val := range vals {
+			mb.reset(val)
+			applyOps(p.operations[b.after:end], mb)
+			if mb.ok {
+				kept = append(kept, mb.val)
+			} else if mb.err != nil && firstErr == nil {
+				firstErr = mb.err
 			}
 		}
+		vals = kept
+	}
+	return append(dst[:from], vals...), firstErr
+}
+
+// trim discards the elements of dst from the given index onwards that the first barrier doesn't need, so that barriers
+// which only keep the last elements, such as TakeLast, don't hold every element while buffering. Elements are discarded
+// once twice the window has been buffered, so each one is only moved once on average.
+func (p *pipeline[T]) trim(dst []T, from int) []T {
+	if len(p.barriers) == 0 {
+		return dst
+	}
+	window := p.barriers[0].window
+	if window < 1 || len(dst)-from < 2*window {
+		return dst
+	}
+	kept := copy(dst[from:], dst[len(dst)-window:])
+	clear(dst[from+kept:]) // don't keep references to the discarded elements around
+	return dst[:from+kept]
+}
+
+func collect[T any](it *iter[T], dst []T) []T {
+	p, report := it.instrumented(it.pipeline())
+	defer report()
+	start := len(dst)
+	result := dst
+	mb := new(maybe[T]) // create a single maybe object to be reused for each iteration, preventing unnecessary allocations
+	it.ForEach(func(val T) {
+		mb.reset(val)
+		p.apply(mb)
 		if mb.ok {
-			result = append(result, mb.val)
+			result = p.trim(append(result, mb.val), start)
 		}
 		mb.ok = false
 	})
+	result, _ = p.flush(result, start) // errors from Try operations are only reported by TryCollect
 	return result
 }
 
-func (it *iter[T]) Channel() <-chan T {
-	ch := make(chan T, len(it.source))
-	it.IntoChannel(ch, CloseChannel(true))
+func (it *iter[T]) CollectN(n int) []T {
+	if n < 1 {
+		return []T{}
+	}
+	result := make([]T, 0, minInt(n, it.collectCap()))
+	it.process(func(val T) bool {
+		result = append(result, val)
+		return len(result) < n
+	})
+	return result
+}
+
+func (it *iter[T]) TryCollect() ([]T, error) {
+	p, report := it.instrumented(it.pipeline())
+	defer report()
+	result := make([]T, 0, it.collectCap())
+	mb := new(maybe[T])
+	for {
+		val, ok := it.Next()
+		if !ok {
+			break
+		}
+		mb.reset(val)
+		p.apply(mb)
+		if mb.err != nil {
+			return nil, mb.err
+		}
+		if mb.ok {
+			result = p.trim(append(result, mb.val), 0)
+		}
+	}
+	if err := it.sourceErr(); err != nil {
+		return nil, err
+	}
+	result, err := p.flush(result, 0)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (it *iter[T]) ForEachUntil(fn func(T) bool) {
+	for {
+		val, ok := it.Next()
+		if !ok || !fn(val) {
+			return
+		}
+	}
+}
+
+func (it *iter[T]) ForEachBatch(n int, fn func([]T)) {
+	if n < 1 {
+		panic("iterator: ForEachBatch size must be positive")
+	}
+	batch := make([]T, 0, minInt(n, maxInt(it.collectCap(), 1)))
+	it.process(func(val T) bool {
+		batch = append(batch, val)
+		if len(batch) == n {
+			fn(batch)
+			batch = batch[:0]
+		}
+		return true
+	})
+	if len(batch) > 0 {
+		fn(batch)
+	}
+}
+
+func (it *iter[T]) TryForEach(fn func(T) error) error {
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return it.sourceErr()
+		}
+		if err := fn(val); err != nil {
+			return err
+		}
+	}
+}
+
+// tryProcess is like process, but stops at the first error returned by a Try operation, the source, or fn, and returns it.
+func (it *iter[T]) tryProcess(fn func(T) error) error {
+	p := it.pipeline()
+	if len(p.barriers) > 0 {
+		vals, err := it.TryCollect()
+		if err != nil {
+			return err
+		}
+		for _, val := range vals {
+			if err := fn(val); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	p, report := it.instrumented(p)
+	defer report()
+	mb := new(maybe[T])
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return it.sourceErr()
+		}
+		mb.reset(val)
+		p.apply(mb)
+		if mb.err != nil {
+			return mb.err
+		}
+		if mb.ok {
+			if err := fn(mb.val); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// process applies the chained operations to the rest of the source, calling fn with each value that survives them until fn
+// returns false. Values are streamed one at a time unless the pipeline contains a barrier, in which case it is collected
+// first.
+func (it *iter[T]) process(fn func(T) bool) {
+	p := it.pipeline()
+	if len(p.barriers) > 0 {
+		for _, val := range it.Collect() {
+			if !fn(val) {
+				return
+			}
+		}
+		return
+	}
+	p, report := it.instrumented(p)
+	defer report()
+	mb := new(maybe[T])
+	for {
+		val, ok := it.Next()
+		if !ok {
+			return
+		}
+		mb.reset(val)
+		p.apply(mb)
+		if mb.ok && !fn(mb.val) {
+			return
+		}
+	}
+}
+
+func (it *iter[T]) Sample(n int) []T {
+	if n < 1 {
+		return []T{}
+	}
+	intn := rand.Intn
+	if r := it.randSource(nil); r != nil {
+		intn = r.Intn
+	}
+	reservoir := make([]T, 0, n)
+	seen := 0
+	it.process(func(val T) bool {
+		seen++
+		if len(reservoir) < n {
+			reservoir = append(reservoir, val)
+		} else if idx := intn(seen); idx < n { // keep the value with probability n/seen
+			reservoir[idx] = val
+		}
+		return true
+	})
+	return reservoir
+}
+
+func (it *iter[T]) Drain() int {
+	n := 0
+	it.process(func(T) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+var (
+	// ErrNoValues is returned by Single when no value survived the chained operations.
+	ErrNoValues = errors.New("iterator: no values")
+	// ErrMultipleValues is returned by Single when more than one value survived the chained operations.
+	ErrMultipleValues = errors.New("iterator: more than one value")
+)
+
+func (it *iter[T]) IsEmpty() bool {
+	empty := true
+	it.process(func(T) bool {
+		empty = false
+		return false
+	})
+	return empty
+}
+
+func (it *iter[T]) Single() (T, error) {
+	var single T
+	found := false
+	err := it.tryProcess(func(val T) error {
+		if found {
+			return ErrMultipleValues
+		}
+		single, found = val, true
+		return nil
+	})
+	switch {
+	case err != nil:
+		return *new(T), err
+	case !found:
+		return single, ErrNoValues
+	}
+	return single, nil
+}
+
+func (it *iter[T]) TopK(k int, less func(a, b T) bool) []T {
+	return it.boundedSort(k, less)
+}
+
+func (it *iter[T]) BottomK(k int, less func(a, b T) bool) []T {
+	return it.boundedSort(k, func(a, b T) bool {
+		return less(b, a)
+	})
+}
+
+// boundedSort applies the chained operations and returns the k greatest values according to less, greatest first. The
+// values kept so far are held in a heap with the least of them on top, so each value only has to be compared with the top
+// to know whether it should replace it.
+func (it *iter[T]) boundedSort(k int, less func(a, b T) bool) []T {
+	if k < 1 {
+		return []T{}
+	}
+	h := &Heap[T]{Sortable[T]{Values: make([]T, 0, k), less: less}}
+	it.process(func(val T) bool {
+		if h.Len() < k {
+			heap.Push(h, val)
+		} else if less(h.Values[0], val) {
+			h.Values[0] = val
+			heap.Fix(h, 0)
+		}
+		return true
+	})
+	sorted := make([]T, h.Len())
+	for idx := len(sorted) - 1; idx >= 0; idx-- {
+		sorted[idx] = heap.Pop(h).(T)
+	}
+	return sorted
+}
+
+func (it *iter[T]) Channel(opts ...IntoChannelOption) <-chan T {
+	ch := it.newChannel(opts)
+	it.IntoChannel(ch, append(opts[:len(opts):len(opts)], CloseChannel(true))...) // clipped, so the caller's slice is never written to
 	return ch
 }
 
-func (it *iter[T]) IntoChannel(ch chan<- T, opts ...IntoChannelOption) {
-	icos := new(intoChannelOptions)
-	for _, opt := range opts {
-		opt(icos)
+// newChannel creates the channel returned by Channel and CollectChannel, with the buffer size set by the given options.
+func (it *iter[T]) newChannel(opts []IntoChannelOption) chan T {
+	buffer := newIntoChannelOptions(opts).buffer
+	if buffer < 0 {
+		buffer = it.SizeHint()
 	}
+	return make(chan T, buffer)
+}
+
+func (it *iter[T]) IntoChannel(ch chan<- T, opts ...IntoChannelOption) {
+	icos := newIntoChannelOptions(opts)
 	go func() {
 		if icos.closeChannel {
 			defer close(ch)
 		}
-		it.ForEach(func(val T) {
-			ch <- val
-		})
+		for {
+			val, ok := it.Next()
+			if !ok || !icos.throttle() || !send(icos.ctx, ch, val) {
+				return
+			}
+		}
 	}()
 }
 
-func (it *iter[T]) CollectChannel() <-chan T {
-	ch := make(chan T, len(it.source))
-	it.CollectIntoChannel(ch, CloseChannel(true))
+func (it *iter[T]) CollectChannel(opts ...IntoChannelOption) <-chan T {
+	ch := it.newChannel(opts)
+	it.CollectIntoChannel(ch, append(opts[:len(opts):len(opts)], CloseChannel(true))...) // clipped, so the caller's slice is never written to
 	return ch
 }
 
 func (it *iter[T]) CollectIntoChannel(ch chan<- T, opts ...IntoChannelOption) {
-	icos := new(intoChannelOptions)
-	for _, opt := range opts {
-		opt(icos)
-	}
+	icos := newIntoChannelOptions(opts)
 	go func() {
 		if icos.closeChannel {
 			defer close(ch)
 		}
-		for _, val := range it.Collect() {
-			ch <- val
+		it.process(func(val T) bool {
+			return icos.throttle() && send(icos.ctx, ch, val)
+		})
+	}()
+}
+
+func (it *iter[T]) Broadcast(chs []chan<- T, opts ...IntoChannelOption) {
+	icos := newIntoChannelOptions(opts)
+	go func() {
+		if icos.closeChannel {
+			defer func() {
+				for _, ch := range chs {
+					close(ch)
+				}
+			}()
 		}
+		it.process(func(val T) bool {
+			if !icos.throttle() {
+				return false
+			}
+			for _, ch := range chs {
+				if !send(icos.ctx, ch, val) {
+					return false
+				}
+			}
+			return true
+		})
 	}()
 }
 
+func (it *iter[T]) FanOut(n int, opts ...IntoChannelOption) []<-chan T {
+	icos := newIntoChannelOptions(opts)
+	n = workerCount(n)
+	var route func(T) int
+	if icos.partitionKey != nil {
+		key, ok := icos.partitionKey.(func(T) any)
+		if !ok {
+			panic(fmt.Sprintf("iterator: the PartitionKey key function doesn't accept the %s elements of the iterator", reflect.TypeOf((*T)(nil)).Elem()))
+		}
+		assigned := make(map[any]int) // the channel each key has been assigned to
+		route = func(val T) int {
+			k := key(val)
+			idx, ok := assigned[k]
+			if !ok {
+				idx = len(assigned) % n
+				assigned[k] = idx
+			}
+			return idx
+		}
+	} else {
+		next := -1
+		route = func(T) int {
+			next = (next + 1) % n
+			return next
+		}
+	}
+	buffer := icos.buffer
+	if buffer < 0 {
+		buffer = (it.SizeHint() + n - 1) / n
+	}
+	chans := make([]chan T, n)
+	outs := make([]<-chan T, n)
+	for idx := range chans {
+		chans[idx] = make(chan T, buffer)
+		outs[idx] = chans[idx]
+	}
+	go func() {
+		defer func() {
+			for _, ch := range chans {
+				close(ch)
+			}
+		}()
+		it.process(func(val T) bool {
+			return icos.throttle() && send(icos.ctx, chans[route(val)], val)
+		})
+	}()
+	return outs
+}
+
+func newIntoChannelOptions(opts []IntoChannelOption) *intoChannelOptions {
+	icos := &intoChannelOptions{ctx: context.Background(), buffer: -1}
+	for _, opt := range opts {
+		opt(icos)
+	}
+	return icos
+}
+
+// send sends the value on the channel, returning false without sending it if the context is cancelled first.
+func send[T any](ctx context.Context, ch chan<- T, val T) bool {
+	if ctx.Err() != nil { // checked first, as select picks randomly if the receiver is also ready
+		return false
+	}
+	select {
+	case ch <- val:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// throttle waits until the next value can be sent according to the Throttle option, returning false if the context is
+// cancelled first.
+func (opts *intoChannelOptions) throttle() bool {
+	if opts.interval <= 0 {
+		return true
+	}
+	if wait := time.Until(opts.nextSend); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-opts.ctx.Done():
+			return false
+		}
+	}
+	opts.nextSend = time.Now().Add(opts.interval)
+	return true
+}
+
 func (it *iter[T]) Reduce(fn func(acc T, next T) T, initial T) T {
 	result := initial
 	it.ForEach(func(val T) {
@@ -186,6 +1146,108 @@ func (it *iter[T]) Reduce(fn func(acc T, next T) T, initial T) T {
 	return result
 }
 
-func (it *iter[T]) Reset() {
+func (it *iter[T]) Clone() Of[T] {
+	it.mu.Lock() // uncontended unless the ThreadSafe option is used, in which case Next holds the same lock
+	defer it.mu.Unlock()
+	if it.stopFunc != nil { // the source is shared, so it's released by whichever copy is stopped first, and only once
+		it.stopFunc = sync.OnceFunc(it.stopFunc)
+	}
+	clone := &iter[T]{
+		nextFunc:    it.nextFunc,
+		readFunc:    it.readFunc,
+		collectFunc: it.collectFunc,
+		nextIndex:   it.nextIndex,
+		size:        it.size,
+		sized:       it.sized,
+		yield:       it.yield,
+		source:      it.source,
+		pipe:        it.pipe.clone(),
+		rand:        it.rand,
+		err:         it.err,
+		threadSafe:  it.threadSafe,
+		stopFunc:    it.stopFunc,
+		resetFunc:   it.resetFunc,
+		closeErr:    it.closeErr,
+		instrument:  it.instrument,
+		trace:       it.trace,
+	}
+	for idx, newOp := range it.pipe.stateful {
+		clone.pipe.operations[idx] = newOp()
+	}
+	return clone
+}
+
+func (it *iter[T]) Stop() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.readFunc = exhausted[T]
+	if !it.threadSafe { // otherwise nextFunc is read without the lock, and synchronizedNext calls readFunc anyway
+		it.nextFunc = exhausted[T]
+	}
+	if it.stopFunc != nil {
+		stop := it.stopFunc
+		it.stopFunc = nil
+		stop()
+	}
+}
+
+func (it *iter[T]) Close() error {
+	it.Stop()
+	if it.closeErr == nil {
+		return nil
+	}
+	return it.closeErr()
+}
+
+// onStop registers a function that releases the source when Stop is called, which runs before any function registered
+// using the OnStop option, as that usually releases whatever produces the source's elements.
+func (it *iter[T]) onStop(fn func()) {
+	next := it.stopFunc
+	it.stopFunc = func() {
+		fn()
+		if next != nil {
+			next()
+		}
+	}
+}
+
+// sourceErr returns the error that ended the source early, if any.
+func (it *iter[T]) sourceErr() error {
+	it.mu.Lock() // the error is set by the source while the lock is held when the ThreadSafe option is used
+	defer it.mu.Unlock()
+	return it.err
+}
+
+// exhausted is the read function of a stopped iterator, which has no elements left.
+func exhausted[T any](*iter[T]) (T, bool) {
+	return *new(T), false
+}
+
+func (it *iter[T]) Reset(opts ...ResetOption) {
+	options := new(resetOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	it.mu.Lock() // uncontended unless the ThreadSafe option is used, in which case Next holds the same lock
+	defer it.mu.Unlock()
 	it.nextIndex = 0
+	if it.resetFunc != nil {
+		it.resetFunc()
+	}
+	if options.clearOperations {
+		operations := it.pipe.operations[:0]
+		if it.threadSafe { // collections on other goroutines may still be reading the operations
+			operations = make([]func(*maybe[T]), 0, cap(operations))
+		}
+		it.pipe = &pipeline[T]{operations: operations}
+	} else if len(it.pipe.rewound) > 0 {
+		p := it.pipe
+		if it.threadSafe { // collections on other goroutines may still be using the operations
+			p = p.clone()
+		}
+		for _, idx := range p.rewound {
+			p.operations[idx] = p.stateful[idx]()
+		}
+		it.pipe = p
+	}
 }
@@ -1,8 +1,14 @@
 package iterator_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
 	"reflect"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/thezmc/iterator"
 )
@@ -100,6 +106,28 @@ func Test_Iterator_Collect_Ints(t *testing.T) {
 			},
 			expected: []int{6},
 		},
+		"sort": {
+			source: []int{3, 1, 2},
+			configFn: func(it iterator.Of[int]) {
+				it.Sort(func(a, b int) bool {
+					return a < b
+				})
+			},
+			expected: []int{1, 2, 3},
+		},
+		"filter, sort, map": {
+			source: []int{9, 4, 7, 2, 8, 1},
+			configFn: func(it iterator.Of[int]) {
+				it.Filter(func(val int) bool {
+					return val%2 == 0 // 4, 2, 8
+				}).Sort(func(a, b int) bool {
+					return a > b // 8, 4, 2
+				}).Map(func(val int) int {
+					return val + 1
+				})
+			},
+			expected: []int{9, 5, 3},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -183,6 +211,13 @@ func Test_Iterator_Collect_Strings(t *testing.T) {
 			},
 			expected: []string{"b"},
 		},
+		"sort": {
+			source: []string{"c", "a", "b"},
+			configFn: func(it iterator.Of[string]) {
+				iterator.SortOrdered(it)
+			},
+			expected: []string{"a", "b", "c"},
+		},
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -383,6 +418,15 @@ func Test_Iterator_Collect_Pointers_Deref(t *testing.T) {
 			},
 			expected: []*person{{"Felicita", 23, true}, {"Luis", 24, false}, {"Juan", 25, true}},
 		},
+		"unique with key func": {
+			source: []*person{{"Felicita", 23, true}, {"Luis", 24, false}, {"Juan", 25, true}, {"Luis", 30, true}, {"Felicita", 23, true}},
+			configFn: func(it iterator.Of[*person]) {
+				it.Unique(iterator.WithKeyFunc(func(val *person) string {
+					return val.name
+				}), iterator.DerefPointers(true))
+			},
+			expected: []*person{{"Felicita", 23, true}, {"Luis", 24, false}, {"Juan", 25, true}},
+		},
 		"map, filter, unique": {
 			source: []*person{{"Felicita", 23, true}, {"Luis", 24, false}, {"Juan", 25, true}, {"Luis", 24, false}, {"Felicita", 23, true}},
 			configFn: func(it iterator.Of[*person]) {
@@ -452,6 +496,15 @@ func Test_Iterator_Reset(t *testing.T) {
 	if nums := iter.Collect(); !reflect.DeepEqual(nums, []int{2, 4, 6, 8, 10}) { // proving that the operations were not reset
 		t.Errorf("Expected [2 4 6 8 10], got %v", nums)
 	}
+	iter.Unique().Sort(func(a, b int) bool {
+		return a > b
+	})
+	iter.Reset(iterator.ClearOperations(true))
+	if nums := iter.Filter(func(val int) bool {
+		return val%2 == 1
+	}).Collect(); !reflect.DeepEqual(nums, []int{1, 3, 5}) {
+		t.Errorf("Expected [1 3 5] with only the new operations applied, got %v", nums)
+	}
 }
 
 func Test_Iterator_Channel(t *testing.T) {
@@ -480,3 +533,821 @@ func Test_Iterator_CollectChannel(t *testing.T) {
 		num += 2
 	}
 }
+
+func Test_Iterator_Channel_ChannelBuffer(t *testing.T) {
+	tests := map[string]struct {
+		opts     []iterator.IntoChannelOption
+		expected int
+	}{
+		"default":    {nil, 5},
+		"bounded":    {[]iterator.IntoChannelOption{iterator.ChannelBuffer(2)}, 2},
+		"unbuffered": {[]iterator.IntoChannelOption{iterator.ChannelBuffer(0)}, 0},
+		"negative":   {[]iterator.IntoChannelOption{iterator.ChannelBuffer(2), iterator.ChannelBuffer(-5)}, 5},
+		"not_closed": {[]iterator.IntoChannelOption{iterator.ChannelBuffer(1), iterator.CloseChannel(false)}, 1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ch := iterator.FromValues(1, 2, 3, 4, 5).Channel(test.opts...)
+			if cap(ch) != test.expected {
+				t.Errorf("expected a buffer of %d, got %d", test.expected, cap(ch))
+			}
+			collected := iterator.FromValues(1, 2, 3, 4, 5).Map(func(val int) int {
+				return val * 2
+			}).CollectChannel(test.opts...)
+			if cap(collected) != test.expected {
+				t.Errorf("expected a buffer of %d, got %d", test.expected, cap(collected))
+			}
+			if result := iterator.FromChannel(ch).Collect(); !reflect.DeepEqual(result, []int{1, 2, 3, 4, 5}) {
+				t.Errorf("expected [1 2 3 4 5], got %v", result)
+			}
+			if result := iterator.FromChannel(collected).Collect(); !reflect.DeepEqual(result, []int{2, 4, 6, 8, 10}) {
+				t.Errorf("expected [2 4 6 8 10], got %v", result)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := iterator.Range(0, 1000, 1).Channel(iterator.ChannelBuffer(1), iterator.WithContext(ctx))
+	<-ch
+	cancel()
+	received := 0
+	for range ch {
+		received++
+	}
+	if received > 2 {
+		t.Errorf("expected the sender to stop once cancelled, got %d more values", received)
+	}
+}
+
+func Test_Iterator_Throttle(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	start := time.Now()
+	result := iterator.FromChannel(iterator.FromValues(1, 2, 3, 4).CollectChannel(iterator.Throttle(interval))).Collect()
+	if elapsed := time.Since(start); elapsed < 3*interval {
+		t.Errorf("expected 4 values to take at least %v, took %v", 3*interval, elapsed)
+	}
+	if !reflect.DeepEqual(result, []int{1, 2, 3, 4}) {
+		t.Errorf("expected [1 2 3 4], got %v", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := iterator.Range(0, 1000, 1).Channel(iterator.Throttle(time.Hour), iterator.WithContext(ctx))
+	if val := <-ch; val != 0 {
+		t.Errorf("expected 0, got %d", val)
+	}
+	cancel()
+	for val := range ch {
+		t.Errorf("expected nothing to be sent while waiting after cancelling, got %d", val)
+	}
+}
+
+func Test_Iterator_Broadcast(t *testing.T) {
+	metrics, persistence := make(chan int), make(chan int, 10)
+	iterator.FromValues(1, 2, 3, 4).Filter(func(val int) bool {
+		return val%2 == 0
+	}).Broadcast([]chan<- int{metrics, persistence}, iterator.CloseChannel(true))
+	sum := 0
+	for val := range metrics {
+		sum += val
+	}
+	if sum != 6 {
+		t.Errorf("expected a sum of 6, got %d", sum)
+	}
+	if result := iterator.FromChannel(persistence).Collect(); !reflect.DeepEqual(result, []int{2, 4}) {
+		t.Errorf("expected [2 4], got %v", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stalled, open := make(chan int), make(chan int, 10)
+	iterator.FromValues(1, 2, 3).Broadcast([]chan<- int{open, stalled}, iterator.WithContext(ctx), iterator.CloseChannel(true))
+	if val := <-open; val != 1 {
+		t.Errorf("expected 1, got %d", val)
+	}
+	cancel() // the sender is stuck on the stalled channel until now
+	for val := range open {
+		t.Errorf("expected nothing to be sent after cancelling, got %d", val)
+	}
+	if _, ok := <-stalled; ok {
+		t.Error("expected the stalled channel to be closed without receiving anything")
+	}
+}
+
+func Test_Iterator_FanOut(t *testing.T) {
+	drain := func(chans []<-chan int) [][]int {
+		results := make([][]int, len(chans))
+		done := make(chan struct{})
+		for idx, ch := range chans {
+			go func() {
+				defer func() { done <- struct{}{} }()
+				results[idx] = []int{}
+				for val := range ch {
+					results[idx] = append(results[idx], val)
+				}
+			}()
+		}
+		for range chans {
+			<-done
+		}
+		return results
+	}
+	tests := map[string]struct {
+		opts     []iterator.IntoChannelOption
+		expected [][]int
+	}{
+		"round_robin": {nil, [][]int{{2, 8, 14}, {4, 10, 16}, {6, 12}}},
+		"partitioned": {
+			[]iterator.IntoChannelOption{iterator.PartitionKey(func(val int) bool { return val%4 == 0 }), iterator.ChannelBuffer(0)},
+			[][]int{{2, 6, 10, 14}, {4, 8, 12, 16}, {}},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			chans := iterator.Range(1, 9, 1).Map(func(val int) int {
+				return val * 2
+			}).FanOut(3, test.opts...)
+			if result := drain(chans); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected FanOut to panic when the key function doesn't match the element type")
+		}
+	}()
+	iterator.FromValues(1).FanOut(2, iterator.PartitionKey(func(s string) string { return s }))
+}
+
+func Test_Iterator_Shuffle(t *testing.T) {
+	source := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	first := iterator.From(source).Shuffle(iterator.ShuffleSeed(42)).Collect()
+	second := iterator.From(source).Shuffle(iterator.ShuffleRand(rand.New(rand.NewSource(42)))).Collect()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same seed to produce the same order, got %v and %v", first, second)
+	}
+	sorted := iterator.SortOrdered(iterator.From(first)).Collect()
+	if !reflect.DeepEqual(sorted, source) {
+		t.Errorf("Expected a permutation of %v, got %v", source, first)
+	}
+	doubled := iterator.From(source).Filter(func(val int) bool {
+		return val <= 3
+	}).Shuffle().Map(func(val int) int {
+		return val * 2
+	}).Collect()
+	if sorted := iterator.SortOrdered(iterator.From(doubled)).Collect(); !reflect.DeepEqual(sorted, []int{2, 4, 6}) {
+		t.Errorf("Expected a permutation of [2 4 6], got %v", doubled)
+	}
+}
+
+func Test_Iterator_Sample(t *testing.T) {
+	source := make([]int, 1000)
+	for i := range source {
+		source[i] = i
+	}
+	isEven := func(val int) bool {
+		return val%2 == 0
+	}
+	sample := iterator.From(source).Filter(isEven).Sample(10)
+	if len(sample) != 10 {
+		t.Fatalf("Expected 10 values, got %v", sample)
+	}
+	if unique := iterator.From(sample).Unique().Collect(); len(unique) != 10 {
+		t.Errorf("Expected 10 distinct values, got %v", sample)
+	}
+	for _, val := range sample {
+		if !isEven(val) {
+			t.Errorf("Expected only even values, got %v", sample)
+		}
+	}
+	if all := iterator.From([]int{1, 2, 3}).Sample(5); !reflect.DeepEqual(all, []int{1, 2, 3}) {
+		t.Errorf("Expected [1 2 3], got %v", all)
+	}
+	if none := iterator.From([]int{1, 2, 3}).Sample(0); len(none) != 0 {
+		t.Errorf("Expected no values, got %v", none)
+	}
+}
+
+func Test_Iterator_Compact(t *testing.T) {
+	one, two := 1, 2
+	if result := iterator.From([]*int{nil, &one, nil, &two}).Compact().Collect(); !reflect.DeepEqual(result, []*int{&one, &two}) {
+		t.Errorf("expected [%p %p], got %v", &one, &two, result)
+	}
+	if result := iterator.From([]string{"a", "", "b", ""}).Compact().Collect(); !reflect.DeepEqual(result, []string{"a", "b"}) {
+		t.Errorf("expected [a b], got %v", result)
+	}
+	type point struct{ x, y int }
+	if result := iterator.From([]point{{0, 0}, {0, 1}, {}}).Compact().Collect(); !reflect.DeepEqual(result, []point{{0, 1}}) {
+		t.Errorf("expected [{0 1}], got %v", result)
+	}
+	if result := iterator.From([][]int{nil, {}, {1}}).Compact().Collect(); !reflect.DeepEqual(result, [][]int{{}, {1}}) {
+		t.Errorf("expected [[] [1]], got %v", result)
+	}
+	if result := iterator.From([]error{nil, errors.New("failed"), nil}).Compact().Collect(); len(result) != 1 || result[0] == nil {
+		t.Errorf("expected a single error, got %v", result)
+	}
+}
+
+func Test_Replace(t *testing.T) {
+	tests := map[string]struct {
+		n        int
+		expected []string
+	}{
+		"first":    {1, []string{"-", "a", "b", "", "c", ""}},
+		"first_2":  {2, []string{"-", "a", "b", "-", "c", ""}},
+		"none":     {0, []string{"", "a", "b", "", "c", ""}},
+		"all":      {-1, []string{"-", "a", "b", "-", "c", "-"}},
+		"too_many": {10, []string{"-", "a", "b", "-", "c", "-"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.Replace(iterator.From([]string{"", "a", "b", "", "c", ""}), "", "-", test.n)
+			if result := it.Collect(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+	if result := iterator.ReplaceAll(iterator.FromValues(1, 0, 2, 0), 0, -1).Collect(); !reflect.DeepEqual(result, []int{1, -1, 2, -1}) {
+		t.Errorf("expected [1 -1 2 -1], got %v", result)
+	}
+}
+
+func Test_Replace_Clone(t *testing.T) {
+	it := iterator.Replace(iterator.FromValues(0, 1, 0), 0, 9, 1)
+	clone := it.Clone()
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{9, 1, 0}) {
+		t.Errorf("expected [9 1 0], got %v", result)
+	}
+	if result := clone.Collect(); !reflect.DeepEqual(result, []int{9, 1, 0}) {
+		t.Errorf("expected the clone to count its own replacements, got %v", result)
+	}
+}
+
+func Test_Iterator_TakeLast_SkipLast(t *testing.T) {
+	source := []int{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	isOdd := func(val int) bool {
+		return val%2 == 1
+	}
+	double := func(val int) int {
+		return val * 2
+	}
+	tests := map[string]test[int]{
+		"take_last":            {source, func(it iterator.Of[int]) { it.Filter(isOdd).TakeLast(2) }, []int{7, 9}},
+		"take_last_then_map":   {source, func(it iterator.Of[int]) { it.Filter(isOdd).TakeLast(3).Map(double) }, []int{10, 14, 18}},
+		"take_last_more":       {source, func(it iterator.Of[int]) { it.TakeLast(20) }, source},
+		"take_last_zero":       {source, func(it iterator.Of[int]) { it.TakeLast(0) }, []int{}},
+		"take_last_after_sort": {source, func(it iterator.Of[int]) { it.Sort(func(a, b int) bool { return a > b }).TakeLast(2) }, []int{2, 1}},
+		"skip_last":            {source, func(it iterator.Of[int]) { it.Filter(isOdd).SkipLast(2) }, []int{1, 3, 5}},
+		"skip_last_then_map":   {source, func(it iterator.Of[int]) { it.SkipLast(6).Map(double) }, []int{2, 4, 6}},
+		"skip_last_more":       {source, func(it iterator.Of[int]) { it.SkipLast(20) }, []int{}},
+		"skip_last_zero":       {source, func(it iterator.Of[int]) { it.SkipLast(0) }, source},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			runCollect(t, test)
+		})
+	}
+
+	long := make([]int, 1000)
+	for i := range long {
+		long[i] = i
+	}
+	if result, err := iterator.From(long).TakeLast(3).TryCollect(); err != nil || !reflect.DeepEqual(result, []int{997, 998, 999}) {
+		t.Errorf("expected [997 998 999], got %v and %v", result, err)
+	}
+	if result := iterator.From(long).TakeLast(3).CollectInto([]int{-1}); !reflect.DeepEqual(result, []int{-1, 997, 998, 999}) {
+		t.Errorf("expected [-1 997 998 999], got %v", result)
+	}
+
+	for _, opts := range [][]iterator.FromOption{nil, {iterator.ThreadSafe(true)}} {
+		it := iterator.From([]int{1, 2, 3, 4, 5}, opts...).SkipLast(2)
+		it.Collect()
+		it.Reset()
+		if result := it.Collect(); !reflect.DeepEqual(result, []int{1, 2, 3}) {
+			t.Errorf("expected [1 2 3] after Reset, got %v", result)
+		}
+	}
+}
+
+func Test_Iterator_TopK_BottomK(t *testing.T) {
+	less := func(a, b int) bool {
+		return a < b
+	}
+	tests := map[string]struct {
+		source      []int
+		k           int
+		top, bottom []int
+	}{
+		"k_of_many": {[]int{5, 1, 9, 3, 7, 2, 8}, 3, []int{9, 8, 7}, []int{1, 2, 3}},
+		"fewer":     {[]int{2, 1}, 5, []int{2, 1}, []int{1, 2}},
+		"ties":      {[]int{4, 4, 1, 4}, 2, []int{4, 4}, []int{1, 4}},
+		"zero":      {[]int{1, 2}, 0, []int{}, []int{}},
+		"empty":     {[]int{}, 2, []int{}, []int{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if top := iterator.From(test.source).TopK(test.k, less); !reflect.DeepEqual(top, test.top) {
+				t.Errorf("expected top %+v, got %+v", test.top, top)
+			}
+			if bottom := iterator.From(test.source).BottomK(test.k, less); !reflect.DeepEqual(bottom, test.bottom) {
+				t.Errorf("expected bottom %+v, got %+v", test.bottom, bottom)
+			}
+		})
+	}
+
+	source := rand.New(rand.NewSource(1)).Perm(1000)
+	top := iterator.From(source).Filter(func(val int) bool {
+		return val%2 == 0
+	}).TopK(10, less)
+	expected := []int{998, 996, 994, 992, 990, 988, 986, 984, 982, 980}
+	if !reflect.DeepEqual(top, expected) {
+		t.Errorf("expected %v, got %v", expected, top)
+	}
+}
+
+func Test_Iterator_WithRand(t *testing.T) {
+	source := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	run := func() ([]int, []int) {
+		it := iterator.From(source, iterator.WithRand(rand.New(rand.NewSource(7))))
+		shuffled := it.Shuffle().Collect()
+		it.Reset()
+		return shuffled, it.Sample(3)
+	}
+	firstShuffle, firstSample := run()
+	secondShuffle, secondSample := run()
+	if !reflect.DeepEqual(firstShuffle, secondShuffle) {
+		t.Errorf("Expected the same shuffle, got %v and %v", firstShuffle, secondShuffle)
+	}
+	if !reflect.DeepEqual(firstSample, secondSample) {
+		t.Errorf("Expected the same sample, got %v and %v", firstSample, secondSample)
+	}
+}
+
+func Test_Iterator_CollectInto(t *testing.T) {
+	it := iterator.From([]int{5, 2, 8, 1}).Filter(func(val int) bool {
+		return val > 1
+	})
+	buf := it.CollectInto([]int{100})
+	if !reflect.DeepEqual(buf, []int{100, 5, 2, 8}) {
+		t.Errorf("Expected [100 5 2 8], got %v", buf)
+	}
+	sorted := iterator.SortOrdered(iterator.From([]int{3, 1, 2}))
+	if result := sorted.CollectInto([]int{9}); !reflect.DeepEqual(result, []int{9, 1, 2, 3}) {
+		t.Errorf("Expected the prefix to be left out of the sort, got %v", result)
+	}
+	first := &buf[0]
+	it.Reset()
+	buf = it.CollectInto(buf[:0])
+	if !reflect.DeepEqual(buf, []int{5, 2, 8}) || &buf[0] != first {
+		t.Errorf("Expected [5 2 8] in the same buffer, got %v", buf)
+	}
+}
+
+func Test_Iterator_TryCollect(t *testing.T) {
+	errOdd := errors.New("odd")
+	halve := func(val int) (int, error) {
+		if val%2 != 0 {
+			return 0, errOdd
+		}
+		return val / 2, nil
+	}
+	positive := func(val int) (bool, error) {
+		if val == 0 {
+			return false, errors.New("zero")
+		}
+		return val > 0, nil
+	}
+	tests := map[string]struct {
+		source   []int
+		configFn func(iterator.Of[int])
+		expected []int
+		err      error
+	}{
+		"no_errors": {
+			source:   []int{4, -2, 8},
+			configFn: func(it iterator.Of[int]) { it.TryMap(halve).TryFilter(positive) },
+			expected: []int{2, 4},
+		},
+		"map_error": {
+			source:   []int{4, 3, 8},
+			configFn: func(it iterator.Of[int]) { it.TryMap(halve) },
+			err:      errOdd,
+		},
+		"filtered_before_error": {
+			source: []int{4, 3, 8},
+			configFn: func(it iterator.Of[int]) {
+				it.Filter(func(val int) bool { return val%2 == 0 }).TryMap(halve)
+			},
+			expected: []int{2, 4},
+		},
+		"error_after_sort": {
+			source:   []int{8, 3, 4},
+			configFn: func(it iterator.Of[int]) { it.Sort(func(a, b int) bool { return a < b }).TryMap(halve) },
+			err:      errOdd,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.From(test.source)
+			test.configFn(it)
+			result, err := it.TryCollect()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+
+	it := iterator.From([]int{4, 3, 8}).TryMap(halve)
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{2, 4}) {
+		t.Errorf("expected Collect to drop the failed value, got %+v", result)
+	}
+}
+
+func Test_Iterator_TryForEach(t *testing.T) {
+	errStop := errors.New("stop")
+	var seen []int
+	err := iterator.From([]int{1, 2, 3, 4}).TryForEach(func(val int) error {
+		if val == 3 {
+			return errStop
+		}
+		seen = append(seen, val)
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected error %v, got %v", errStop, err)
+	}
+	if !reflect.DeepEqual(seen, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", seen)
+	}
+}
+
+func Test_Iterator_ForEachUntil(t *testing.T) {
+	it := iterator.From([]int{1, 2, 3, 4, 5})
+	var seen []int
+	it.ForEachUntil(func(val int) bool {
+		seen = append(seen, val)
+		return val < 3
+	})
+	if !reflect.DeepEqual(seen, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", seen)
+	}
+	if rest := it.Collect(); !reflect.DeepEqual(rest, []int{4, 5}) {
+		t.Errorf("expected the rest of the iterator to be left unconsumed, got %v", rest)
+	}
+}
+
+func Test_Iterator_ForEachBatch(t *testing.T) {
+	tests := map[string]struct {
+		source   []int
+		n        int
+		expected [][]int
+	}{
+		"uneven": {
+			source:   []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			n:        2,
+			expected: [][]int{{2, 4}, {6, 8}, {10}},
+		},
+		"exact": {
+			source:   []int{1, 2, 3, 4},
+			n:        2,
+			expected: [][]int{{2, 4}},
+		},
+		"empty": {
+			source: []int{1, 3},
+			n:      2,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var batches [][]int
+			iterator.From(test.source).Filter(func(val int) bool {
+				return val%2 == 0
+			}).ForEachBatch(test.n, func(batch []int) {
+				batches = append(batches, slices.Clone(batch))
+			})
+			if !reflect.DeepEqual(batches, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, batches)
+			}
+		})
+	}
+}
+
+func Test_Iterator_CollectN(t *testing.T) {
+	read := 0
+	it := iterator.FromFunc(func() (int, bool) {
+		read++
+		return read, true
+	}).Filter(func(val int) bool {
+		return val%3 == 0
+	})
+	if result := it.CollectN(2); !reflect.DeepEqual(result, []int{3, 6}) {
+		t.Errorf("expected [3 6], got %v", result)
+	}
+	if read != 6 {
+		t.Errorf("expected the source to be read up to the second match, got %d reads", read)
+	}
+	if result := it.CollectN(0); !reflect.DeepEqual(result, []int{}) {
+		t.Errorf("expected an empty slice, got %v", result)
+	}
+	sorted := iterator.From([]int{5, 1, 4, 2}).Sort(func(a, b int) bool {
+		return a < b
+	}).CollectN(3)
+	if !reflect.DeepEqual(sorted, []int{1, 2, 4}) {
+		t.Errorf("expected [1 2 4], got %v", sorted)
+	}
+	if short := iterator.From([]int{1, 2}).CollectN(5); !reflect.DeepEqual(short, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", short)
+	}
+}
+
+func Test_Iterator_Drain(t *testing.T) {
+	mapped := 0
+	it := iterator.From([]int{1, 2, 3, 4, 5}).Map(func(val int) int {
+		mapped++
+		return val
+	}).Filter(func(val int) bool {
+		return val > 2
+	})
+	if n := it.Drain(); n != 3 {
+		t.Errorf("expected 3 values to survive, got %d", n)
+	}
+	if mapped != 5 {
+		t.Errorf("expected every value to be mapped, got %d", mapped)
+	}
+	if n := it.Drain(); n != 0 {
+		t.Errorf("expected a drained iterator to have nothing left, got %d", n)
+	}
+}
+
+func Test_Iterator_IsEmpty(t *testing.T) {
+	isBig := func(val int) bool {
+		return val > 10
+	}
+	if !iterator.From([]int{1, 2, 3}).Filter(isBig).IsEmpty() {
+		t.Error("expected no values to survive the filter")
+	}
+	it := iterator.From([]int{1, 20, 30}).Filter(isBig)
+	if it.IsEmpty() {
+		t.Error("expected values to survive the filter")
+	}
+	if rest := it.Collect(); !reflect.DeepEqual(rest, []int{30}) {
+		t.Errorf("expected IsEmpty to stop at the first surviving value, leaving [30], got %v", rest)
+	}
+}
+
+func Test_Iterator_Single(t *testing.T) {
+	errBad := errors.New("bad value")
+	tests := map[string]struct {
+		source   []int
+		expected int
+		err      error
+	}{
+		"one": {
+			source:   []int{1, 4, 5},
+			expected: 4,
+		},
+		"none": {
+			source: []int{1, 5},
+			err:    iterator.ErrNoValues,
+		},
+		"several": {
+			source: []int{2, 4, 6},
+			err:    iterator.ErrMultipleValues,
+		},
+		"failed": {
+			source: []int{4, -1},
+			err:    errBad,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			val, err := iterator.From(test.source).TryFilter(func(val int) (bool, error) {
+				if val < 0 {
+					return false, errBad
+				}
+				return val%2 == 0, nil
+			}).Single()
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+			if val != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, val)
+			}
+		})
+	}
+}
+
+func Test_Iterator_State_Resume(t *testing.T) {
+	source := []int{1, 2, 3, 4, 5, 6, 7}
+	isOdd := func(val int) bool {
+		return val%2 == 1
+	}
+	var saved iterator.IterState
+	var processed []int
+	it := iterator.From(source).Filter(isOdd)
+	for val := range it.Seq() {
+		processed = append(processed, val)
+		saved = it.State()
+		if len(processed) == 2 { // stop as if the job had crashed after saving its state
+			break
+		}
+	}
+	if saved.Index != 3 {
+		t.Errorf("expected the state to record 3 elements read, got %d", saved.Index)
+	}
+	encoded, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored iterator.IterState
+	if err := json.Unmarshal(encoded, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if rest := iterator.Resume(source, restored).Filter(isOdd).Collect(); !reflect.DeepEqual(rest, []int{5, 7}) {
+		t.Errorf("expected [5 7] after resuming, got %v", rest)
+	}
+	if rest := iterator.Resume(source, iterator.IterState{Index: 10}).Collect(); !reflect.DeepEqual(rest, []int{}) {
+		t.Errorf("expected resuming past the end to be empty, got %v", rest)
+	}
+}
+
+func Test_Iterator_IntoChannel_WithContext(t *testing.T) {
+	for name, into := range map[string]func(iterator.Of[int], chan<- int, ...iterator.IntoChannelOption){
+		"IntoChannel":        iterator.Of[int].IntoChannel,
+		"CollectIntoChannel": iterator.Of[int].CollectIntoChannel,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			ch := make(chan int)
+			into(iterator.Range(0, 1000, 1), ch, iterator.WithContext(ctx), iterator.CloseChannel(true))
+			if val := <-ch; val != 0 {
+				t.Errorf("Expected 0, got %d", val)
+			}
+			cancel()
+			received := 0
+			for range ch { // the channel is only closed once the goroutine has stopped sending
+				received++
+			}
+			if received > 1 { // a send may already have been ready when the context was cancelled
+				t.Errorf("Expected the sends to stop once the context was cancelled, got %d more values", received)
+			}
+		})
+	}
+}
+
+func Test_Iterator_CollectIntoChannel_Streams(t *testing.T) {
+	ch := make(chan int)
+	processed := make(chan int, 10)
+	iterator.Range(0, 10, 1).Map(func(val int) int {
+		processed <- val
+		return val * 2
+	}).CollectIntoChannel(ch, iterator.CloseChannel(true))
+	if val := <-ch; val != 0 {
+		t.Errorf("Expected 0, got %d", val)
+	}
+	if n := len(processed); n > 2 { // the first value, and possibly the one blocked waiting to be sent
+		t.Errorf("Expected the values to be streamed, but %d were processed before the first was received", n)
+	}
+	for range ch {
+	}
+}
+
+func Test_Iterator_Unique_WithKeyFunc_Mismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Unique to panic when the key function doesn't accept the elements")
+		}
+	}()
+	iterator.From([]int{1, 2}).Unique(iterator.WithKeyFunc(func(val string) string {
+		return val
+	}))
+}
+
+func Test_Iterator_Unique_MaxEntries(t *testing.T) {
+	source := []int{1, 2, 1, 3, 4, 2, 4, 1}
+	tests := map[string]struct {
+		maxEntries int
+		expected   []int
+	}{
+		"unbounded": {0, []int{1, 2, 3, 4}},
+		"bounded":   {2, []int{1, 2, 3, 4, 2, 1}}, // 2 is forgotten when 3 arrives, and 1 when 4 arrives
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.From(source).Unique(iterator.MaxEntries(test.maxEntries)).Collect()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_Iterator_Unique_KeepLast(t *testing.T) {
+	type upsert struct {
+		id      int
+		version int
+	}
+	source := []upsert{{1, 1}, {2, 1}, {1, 2}, {3, 1}, {2, 2}, {4, 1}}
+	result := iterator.From(source).Filter(func(u upsert) bool {
+		return u.id != 4
+	}).Unique(iterator.KeepLast(true), iterator.WithKeyFunc(func(u upsert) int {
+		return u.id
+	})).Map(func(u upsert) upsert {
+		u.version *= 10
+		return u
+	}).Collect()
+	expected := []upsert{{1, 20}, {3, 10}, {2, 20}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	parallel := iterator.From(source, iterator.Parallel(2)).Unique(iterator.KeepLast(true), iterator.WithKeyFunc(func(u upsert) int {
+		return u.id
+	})).Collect()
+	if expected := []upsert{{1, 2}, {3, 1}, {2, 2}, {4, 1}}; !reflect.DeepEqual(parallel, expected) {
+		t.Errorf("expected %+v when collecting in parallel, got %+v", expected, parallel)
+	}
+}
+
+func Test_Iterator_Clone(t *testing.T) {
+	it := iterator.From([]int{1, 2, 2, 3, 4, 4, 5}).Map(func(val int) int {
+		return val * 10
+	}).Unique()
+	it.Next()
+	clone := it.Clone()
+	evens := clone.Filter(func(val int) bool {
+		return val%20 == 0
+	}).Collect()
+	if expected := []int{20, 40}; !reflect.DeepEqual(evens, expected) {
+		t.Errorf("expected %v from the clone, got %v", expected, evens)
+	}
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{20, 30, 40, 50}) {
+		t.Errorf("expected the original to be unaffected by the clone, got %v", result)
+	}
+	it.Reset()
+	if result := it.Clone().Collect(); !reflect.DeepEqual(result, []int{10, 20, 30, 40, 50}) {
+		t.Errorf("expected a clone to start with no values seen by Unique, got %v", result)
+	}
+}
+
+func Test_Iterator_Len_SizeHint(t *testing.T) {
+	double := func(val int) int {
+		return val * 2
+	}
+	tests := map[string]struct {
+		it       iterator.Of[int]
+		len      int
+		known    bool
+		sizeHint int
+	}{
+		"slice": {
+			it:       iterator.From([]int{1, 2, 3}),
+			len:      3,
+			known:    true,
+			sizeHint: 3,
+		},
+		"range with map and sort": {
+			it: iterator.Range(0, 10, 2).Map(double).Sort(func(a, b int) bool {
+				return a > b
+			}),
+			len:      5,
+			known:    true,
+			sizeHint: 5,
+		},
+		"filtered": {
+			it: iterator.From([]int{1, 2, 3}).Filter(func(val int) bool {
+				return val > 1
+			}),
+			sizeHint: 3,
+		},
+		"function": {
+			it: iterator.FromFunc(func() (int, bool) {
+				return 0, false
+			}),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if n, known := test.it.Len(); n != test.len || known != test.known {
+				t.Errorf("expected Len to return %d, %t, got %d, %t", test.len, test.known, n, known)
+			}
+			if hint := test.it.SizeHint(); hint != test.sizeHint {
+				t.Errorf("expected a size hint of %d, got %d", test.sizeHint, hint)
+			}
+		})
+	}
+
+	it := iterator.From([]int{1, 2, 3, 4})
+	it.Next()
+	if n, _ := it.Len(); n != 3 {
+		t.Errorf("expected 3 elements left after calling Next, got %d", n)
+	}
+	if result := it.Collect(); cap(result) != 3 {
+		t.Errorf("expected Collect to allocate for the 3 elements left, got a capacity of %d", cap(result))
+	}
+	if n, known := it.Len(); n != 0 || !known {
+		t.Errorf("expected an exhausted iterator to have 0 elements left, got %d, %t", n, known)
+	}
+}
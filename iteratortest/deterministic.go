@@ -0,0 +1,114 @@
+package iteratortest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+// Deterministic checks that the pipeline built by build gives the same result every time it's collected, and that
+// collecting it doesn't change the source. The pipeline is built and collected twice, each time over a fresh copy of the
+// source slice, and the results are compared. Values are compared by following pointers, so a Map function that mutates
+// the elements it's given is caught even though both results point to the same values. Randomness, such as an unseeded
+// Shuffle, is reported the same way.
+func Deterministic[T any](t testing.TB, source []T, build func(iterator.Of[T]) iterator.Of[T]) {
+	t.Helper()
+	before := renderAll(source)
+	first := renderAll(build(iterator.From(source, iterator.CopySource(true))).Collect())
+	if idx, ok := firstDifference(before, renderAll(source)); ok {
+		t.Errorf("expected collecting the pipeline to leave the source unchanged, but index %d changed from %s to %s", idx, before[idx], renderAll(source)[idx])
+		return
+	}
+	second := renderAll(build(iterator.From(source, iterator.CopySource(true))).Collect())
+	if len(first) != len(second) {
+		t.Errorf("expected the pipeline to collect the same number of values every time, got %d and %d", len(first), len(second))
+		return
+	}
+	if idx, ok := firstDifference(first, second); ok {
+		t.Errorf("expected the pipeline to collect the same values every time, but index %d was %s and then %s", idx, first[idx], second[idx])
+	}
+}
+
+// firstDifference returns the first index at which two renderings of the same length differ.
+func firstDifference(a, b []string) (int, bool) {
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// renderAll renders each of the given values, following pointers, so that they can be compared after the values they
+// point to have changed.
+func renderAll[T any](vals []T) []string {
+	rendered := make([]string, len(vals))
+	for idx, val := range vals {
+		b := new(strings.Builder)
+		render(b, reflect.ValueOf(&val).Elem(), make(map[uintptr]bool))
+		rendered[idx] = b.String()
+	}
+	return rendered
+}
+
+// render writes a representation of v to b that includes the values behind any pointers, rather than their addresses.
+// Pointers already being rendered are only written once, so cyclic values terminate.
+func render(b *strings.Builder, v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		if seen[v.Pointer()] {
+			b.WriteString("<cycle>")
+			return
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		b.WriteString("&")
+		render(b, v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		render(b, v.Elem(), seen)
+	case reflect.Struct:
+		b.WriteString("{")
+		for i := 0; i < v.NumField(); i++ {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(b, "%s:", v.Type().Field(i).Name)
+			render(b, v.Field(i), seen)
+		}
+		b.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			render(b, v.Index(i), seen)
+		}
+		b.WriteString("]")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		for mi := v.MapRange(); mi.Next(); {
+			entry := new(strings.Builder)
+			render(entry, mi.Key(), seen)
+			entry.WriteString(":")
+			render(entry, mi.Value(), seen)
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries) // map iteration order is random, which isn't the kind of nondeterminism being checked for
+		fmt.Fprintf(b, "map[%s]", strings.Join(entries, " "))
+	default:
+		fmt.Fprint(b, v)
+	}
+}
@@ -0,0 +1,76 @@
+package iteratortest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+	"github.com/thezmc/iterator/iteratortest"
+)
+
+// recorder is a testing.TB that records failures instead of failing the test, so failing checks can be tested.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type counter struct {
+	n int
+}
+
+func Test_Deterministic(t *testing.T) {
+	tests := map[string]struct {
+		source func() []*counter
+		build  func(iterator.Of[*counter]) iterator.Of[*counter]
+		err    string
+	}{
+		"deterministic": {
+			source: func() []*counter { return []*counter{{1}, {2}, {3}} },
+			build: func(it iterator.Of[*counter]) iterator.Of[*counter] {
+				return it.Filter(func(c *counter) bool { return c.n > 1 })
+			},
+		},
+		"mutating_map": {
+			source: func() []*counter { return []*counter{{1}, {2}, {3}} },
+			build: func(it iterator.Of[*counter]) iterator.Of[*counter] {
+				return it.Map(func(c *counter) *counter {
+					c.n *= 2
+					return c
+				})
+			},
+			err: "expected collecting the pipeline to leave the source unchanged, but index 0 changed from &{n:1} to &{n:2}",
+		},
+		"random": {
+			source: func() []*counter {
+				source := make([]*counter, 100)
+				for idx := range source {
+					source[idx] = &counter{idx}
+				}
+				return source
+			},
+			build: func(it iterator.Of[*counter]) iterator.Of[*counter] {
+				return it.Shuffle()
+			},
+			err: "expected the pipeline to collect the same values every time",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			iteratortest.Deterministic(r, test.source(), test.build)
+			if test.err == "" && len(r.errors) > 0 {
+				t.Errorf("expected no errors, got %v", r.errors)
+			}
+			if test.err != "" && (len(r.errors) != 1 || !strings.HasPrefix(r.errors[0], test.err)) {
+				t.Errorf("expected an error starting with %q, got %v", test.err, r.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package iteratortest
+
+import (
+	"time"
+
+	"github.com/thezmc/iterator"
+)
+
+// FlakyRead returns a read function, as taken by iterator.WithRetry, that pulls its elements from src but fails every
+// failEvery-th call with err. A failed call doesn't consume an element, so calling the function again after an error
+// resumes where it left off, as a retried read of a remote source would. Failures are determined by the number of calls
+// alone, so a test that retries them gets the same sequence of failures every time it runs. If failEvery is less than 1,
+// no calls fail; if it's 1, every call does.
+func FlakyRead[T any](src iterator.Of[T], failEvery int, err error) func() (T, bool, error) {
+	calls := 0
+	return func() (T, bool, error) {
+		calls++
+		if failEvery > 0 && calls%failEvery == 0 {
+			return *new(T), false, err
+		}
+		val, ok := src.Next()
+		return val, ok, nil
+	}
+}
+
+// Flaky returns a new iterator that pulls its elements from src, failing its failEvery-th read with err. As iterators
+// can't resume after an error, the iteration ends at the first failure, and TryCollect and TryForEach return an error
+// wrapping err, so the error handling of a pipeline can be tested deterministically. To test how a source recovers from
+// failures, pass FlakyRead to iterator.WithRetry instead. If failEvery is less than 1, no reads fail.
+func Flaky[T any](src iterator.Of[T], failEvery int, err error) iterator.Of[T] {
+	return iterator.WithRetry(FlakyRead(src, failEvery, err), iterator.RetryPolicy{MaxAttempts: 1})
+}
+
+// Slow returns a new iterator that pulls its elements from src, waiting for delay before each read, so timeouts and
+// prefetching can be tested against a source with a known latency.
+func Slow[T any](src iterator.Of[T], delay time.Duration) iterator.Of[T] {
+	return iterator.FromFunc(func() (T, bool) {
+		time.Sleep(delay)
+		return src.Next()
+	})
+}
@@ -0,0 +1,84 @@
+package iteratortest_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thezmc/iterator"
+	"github.com/thezmc/iterator/iteratortest"
+)
+
+var errInjected = errors.New("injected failure")
+
+func Test_Flaky(t *testing.T) {
+	tests := map[string]struct {
+		failEvery int
+		expected  []int
+		err       error
+	}{
+		"fails on the third read": {
+			failEvery: 3,
+			expected:  nil,
+			err:       errInjected,
+		},
+		"never fails": {
+			failEvery: 0,
+			expected:  []int{1, 2, 3, 4, 5},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iteratortest.Flaky(iterator.From([]int{1, 2, 3, 4, 5}), test.failEvery, errInjected)
+			result, err := it.TryCollect()
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_FlakyRead_Retry(t *testing.T) {
+	tests := map[string]struct {
+		attempts int
+		expected []int
+		err      error
+	}{
+		"recovers when retried": {
+			attempts: 2,
+			expected: []int{1, 2, 3, 4, 5},
+		},
+		"fails without retries": {
+			attempts: 1,
+			expected: nil,
+			err:      errInjected,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			read := iteratortest.FlakyRead(iterator.From([]int{1, 2, 3, 4, 5}), 2, errInjected)
+			result, err := iterator.WithRetry(read, iterator.RetryPolicy{MaxAttempts: test.attempts}).TryCollect()
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_Slow(t *testing.T) {
+	start := time.Now()
+	result := iteratortest.Slow(iterator.From([]int{1, 2, 3}), 5*time.Millisecond).Collect()
+	if !reflect.DeepEqual(result, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", result)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("expected each read to be delayed, but collecting took %v", elapsed)
+	}
+}
@@ -0,0 +1,78 @@
+// Package iteratortest provides utilities for testing implementations of the iterator.Of interface, the sources they read
+// from, and the pipelines built on them.
+package iteratortest
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+// Stress checks that iterators returned by newIt meet the concurrency contract of the ThreadSafe option, so that custom
+// sources can be verified the same way as the built-in ones. newIt must return a fresh, thread-safe iterator over the same
+// finite source every time it's called.
+//
+// First, one iterator is drained by the given number of goroutines calling Next concurrently, and the number of elements
+// they received in total is compared with the number received by a single goroutine. Then, the goroutines hammer another
+// iterator with a random interleaving of Next, Reset, and Collect calls, determined by the seed, for the given number of
+// calls each. Data races in the second phase are only reported when the tests are run with the -race flag.
+func Stress[T any](t testing.TB, newIt func() iterator.Of[T], goroutines, calls int, seed int64) {
+	t.Helper()
+	expected := 0
+	sequential := newIt()
+	for _, ok := sequential.Next(); ok; _, ok = sequential.Next() {
+		expected++
+	}
+
+	var received int64
+	mu := sync.Mutex{}
+	it := newIt()
+	run(goroutines, func(int) {
+		count := 0
+		for _, ok := it.Next(); ok; _, ok = it.Next() {
+			count++
+		}
+		mu.Lock()
+		received += int64(count)
+		mu.Unlock()
+	})
+	if received != int64(expected) {
+		t.Errorf("expected %d elements to be returned by concurrent calls to Next, got %d", expected, received)
+	}
+
+	it = newIt()
+	run(goroutines, func(worker int) {
+		r := rand.New(rand.NewSource(seed + int64(worker)))
+		for i := 0; i < calls; i++ {
+			switch r.Intn(10) {
+			case 0:
+				it.Reset()
+			case 1:
+				if collected := it.Collect(); len(collected) > expected {
+					t.Errorf("expected at most %d elements to be collected, got %d", expected, len(collected))
+				}
+			default:
+				it.Next()
+			}
+			if r.Intn(4) == 0 {
+				runtime.Gosched()
+			}
+		}
+	})
+}
+
+// run calls fn from the given number of goroutines, passing each its index, and waits for all of them to return.
+func run(goroutines int, fn func(worker int)) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			fn(worker)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+package iteratortest_test
+
+import (
+	"testing"
+
+	"github.com/thezmc/iterator"
+	"github.com/thezmc/iterator/iteratortest"
+)
+
+func Test_Stress(t *testing.T) {
+	tests := map[string]func() iterator.Of[int]{
+		"slice": func() iterator.Of[int] {
+			return iterator.From([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, iterator.ThreadSafe(true))
+		},
+		"range": func() iterator.Of[int] {
+			return iterator.Range(0, 100, 3, iterator.ThreadSafe(true))
+		},
+		"func": func() iterator.Of[int] {
+			n := 0
+			return iterator.FromFunc(func() (int, bool) {
+				n++
+				return n, n <= 50
+			}, iterator.ThreadSafe(true))
+		},
+		"prefetched_func": func() iterator.Of[int] {
+			n := 0
+			return iterator.FromFunc(func() (int, bool) {
+				n++
+				return n, n <= 50
+			}, iterator.ThreadSafe(true), iterator.Prefetch(4))
+		},
+	}
+	for name, newIt := range tests {
+		t.Run(name, func(t *testing.T) {
+			iteratortest.Stress(t, newIt, 8, 200, 1)
+		})
+	}
+}
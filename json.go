@@ -0,0 +1,119 @@
+package iterator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeJSON returns a new iterator over the elements of the JSON array read from r, decoding them as values of type T one
+// at a time as they are requested, so arrays much larger than memory can be processed. If the input isn't an array or an
+// element can't be decoded, the iteration ends and the error is reported by TryCollect and TryForEach, along with the index
+// of the element. As with FromFunc, Reset has no effect on the elements returned. The options are the same as for From,
+// although CopySource has no effect. DecodeJSON only reads arrays; a stream of values that aren't wrapped in an array,
+// such as newline-delimited JSON, is read by TransformJSON.
+func DecodeJSON[T any](r io.Reader, opts ...FromOption) Of[T] {
+	dec := json.NewDecoder(r)
+	started, done := false, false
+	return newIter(nil, func(it *iter[T]) (T, bool) {
+		var val T
+		if done {
+			return val, false
+		}
+		if !started {
+			started = true
+			if err := expectDelim(dec, '['); err != nil {
+				done, it.err = true, err
+				return val, false
+			}
+		}
+		if !dec.More() {
+			done = true
+			if err := expectDelim(dec, ']'); err != nil {
+				it.err = err
+			}
+			return val, false
+		}
+		if err := dec.Decode(&val); err != nil {
+			done, it.err = true, fmt.Errorf("iterator: decoding JSON array element %d: %w", it.nextIndex, err)
+			return val, false
+		}
+		it.nextIndex++
+		return val, true
+	}, 0, opts)
+}
+
+// expectDelim reads the next token from dec, returning an error if it isn't the given delimiter. The end of the input is
+// reported as io.ErrUnexpectedEOF, as an array was expected to continue.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return fmt.Errorf("iterator: decoding JSON array: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("iterator: decoding JSON array: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// TransformJSON decodes a stream of JSON values of type T from r, such as newline-delimited JSON, passes them through the
+// pipeline built by build, and writes each value left to w as a line of JSON. Values are decoded, processed, and written one
+// at a time, so memory use doesn't grow with the size of the input unless the pipeline contains an operation that needs
+// every value at once, such as Sort. TransformJSON stops at the first error, whether it comes from decoding, a TryMap or
+// TryFilter function, or writing, and returns it. Anything written before the error is left in w. TransformJSON reads and
+// writes streams of values rather than JSON arrays; to transform an array, pass an iterator returned by DecodeJSON through
+// the pipeline to EncodeJSON instead, which stream the elements the same way.
+func TransformJSON[T any](r io.Reader, w io.Writer, build func(Of[T]) Of[T]) error {
+	dec := json.NewDecoder(r)
+	src := newIter(nil, func(it *iter[T]) (T, bool) {
+		var val T
+		if it.err != nil {
+			return val, false
+		}
+		if err := dec.Decode(&val); err != nil {
+			if !errors.Is(err, io.EOF) {
+				it.err = fmt.Errorf("iterator: decoding JSON value %d: %w", it.nextIndex, err)
+			}
+			return val, false
+		}
+		it.nextIndex++
+		return val, true
+	}, 0, nil)
+	enc := json.NewEncoder(w)
+	if err := tryEach(build(src), func(val T) error {
+		return enc.Encode(val)
+	}); err != nil {
+		return err
+	}
+	return src.sourceErr()
+}
+
+// EncodeJSON applies all of the chained operations to the iterator and writes the values left to w as a JSON array,
+// encoding and writing one value at a time, so the values are never all held in memory unless the pipeline contains an
+// operation that needs every value at once, such as Sort. EncodeJSON stops at the first error, whether it comes from
+// encoding, a TryMap or TryFilter function, the source, or writing, and returns it. The array written before the error is
+// left unterminated in w, so a partial export can't be mistaken for a complete one. The elements are written by a
+// json.Encoder, which ends each of them with a newline, so the array has one element per line. To write the values as
+// newline-delimited JSON instead, without the enclosing array, use TransformJSON.
+func EncodeJSON[T any](it Of[T], w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	sep := ""
+	if err := tryEach(it, func(val T) error {
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		sep = ","
+		return enc.Encode(val)
+	}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
@@ -0,0 +1,208 @@
+package iterator_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+type reading struct {
+	Sensor string  `json:"sensor"`
+	Value  float64 `json:"value"`
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func Test_TransformJSON(t *testing.T) {
+	errNegative := errors.New("negative reading")
+	calibrate := func(it iterator.Of[reading]) iterator.Of[reading] {
+		return it.TryMap(func(r reading) (reading, error) {
+			if r.Value < 0 {
+				return r, errNegative
+			}
+			r.Value *= 2
+			return r, nil
+		}).Filter(func(r reading) bool {
+			return r.Sensor != "ignored"
+		})
+	}
+	tests := map[string]struct {
+		input    string
+		expected string
+		err      string
+	}{
+		"stream": {
+			input:    `{"sensor":"a","value":1} {"sensor":"ignored","value":2}` + "\n" + `{"sensor":"b","value":1.5}`,
+			expected: "{\"sensor\":\"a\",\"value\":2}\n{\"sensor\":\"b\",\"value\":3}\n",
+		},
+		"decode_error": {
+			input:    `{"sensor":"a","value":1} {"sensor":`,
+			expected: "{\"sensor\":\"a\",\"value\":2}\n",
+			err:      "iterator: decoding JSON value 1: unexpected EOF",
+		},
+		"try_error": {
+			input:    `{"sensor":"a","value":1} {"sensor":"b","value":-1} {"sensor":"c","value":1}`,
+			expected: "{\"sensor\":\"a\",\"value\":2}\n",
+			err:      errNegative.Error(),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			out := new(strings.Builder)
+			err := iterator.TransformJSON(strings.NewReader(test.input), out, calibrate)
+			if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out.String())
+			}
+		})
+	}
+
+	err := iterator.TransformJSON(strings.NewReader(`{"value":1}`), failingWriter{}, calibrate)
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the write error, got %v", err)
+	}
+	var syntaxErr *json.SyntaxError
+	err = iterator.TransformJSON(strings.NewReader(`{"value":}`), new(strings.Builder), calibrate)
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("expected a wrapped *json.SyntaxError, got %v", err)
+	}
+}
+
+func Test_EncodeJSON(t *testing.T) {
+	errNegative := errors.New("negative reading")
+	tests := map[string]struct {
+		source   []reading
+		expected string
+		err      error
+	}{
+		"array": {
+			source:   []reading{{"a", 1}, {"ignored", 2}, {"b", 1.5}},
+			expected: "[{\"sensor\":\"a\",\"value\":1}\n,{\"sensor\":\"b\",\"value\":1.5}\n]",
+		},
+		"empty": {
+			source:   []reading{},
+			expected: `[]`,
+		},
+		"try_error": {
+			source:   []reading{{"a", 1}, {"b", -1}},
+			expected: "[{\"sensor\":\"a\",\"value\":1}\n",
+			err:      errNegative,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.From(test.source).Filter(func(r reading) bool {
+				return r.Sensor != "ignored"
+			}).TryMap(func(r reading) (reading, error) {
+				if r.Value < 0 {
+					return r, errNegative
+				}
+				return r, nil
+			})
+			out := new(strings.Builder)
+			if err := iterator.EncodeJSON(it, out); !errors.Is(err, test.err) {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+			if out.String() != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, out.String())
+			}
+			if test.err == nil && !json.Valid([]byte(out.String())) {
+				t.Errorf("expected valid JSON, got %q", out.String())
+			}
+		})
+	}
+
+	var unsupported *json.UnsupportedValueError
+	if err := iterator.EncodeJSON(iterator.FromValues(math.Inf(1)), new(strings.Builder)); !errors.As(err, &unsupported) {
+		t.Errorf("expected a *json.UnsupportedValueError, got %v", err)
+	}
+	if err := iterator.EncodeJSON(iterator.FromValues(1), failingWriter{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the write error, got %v", err)
+	}
+}
+
+func Test_DecodeJSON(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected []reading
+		err      string
+	}{
+		"array": {
+			input:    `[{"sensor":"a","value":1}, {"sensor":"ignored","value":2},` + "\n" + `{"sensor":"b","value":1.5}]`,
+			expected: []reading{{"a", 1}, {"b", 1.5}},
+		},
+		"empty": {
+			input:    ` [ ] `,
+			expected: []reading{},
+		},
+		"not_an_array": {
+			input: `{"sensor":"a","value":1}`,
+			err:   "iterator: decoding JSON array: expected [, got {",
+		},
+		"empty_input": {
+			input: ``,
+			err:   "iterator: decoding JSON array: unexpected EOF",
+		},
+		"unterminated": {
+			input: `[{"sensor":"a","value":1}`,
+			err:   "iterator: decoding JSON array element 1: unexpected end of JSON input",
+		},
+		"bad_element": {
+			input: `[{"sensor":"a","value":1}, {"sensor":"b","value":"high"}]`,
+			err:   "iterator: decoding JSON array element 1: json: cannot unmarshal string into Go struct field reading.value of type float64",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := iterator.DecodeJSON[reading](strings.NewReader(test.input)).Filter(func(r reading) bool {
+				return r.Sensor != "ignored"
+			}).TryCollect()
+			if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+				t.Errorf("expected error %q, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+
+	it := iterator.DecodeJSON[int](strings.NewReader(`[1, 2, 3]`))
+	if val, ok := it.Next(); !ok || val != 1 {
+		t.Errorf("expected 1, got %d", val)
+	}
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{2, 3}) {
+		t.Errorf("expected [2 3], got %v", result)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("expected the iterator to be exhausted")
+	}
+
+	prefetched := iterator.DecodeJSON[int](strings.NewReader(`[1, 2, "three"]`), iterator.Prefetch(2))
+	if result, err := prefetched.TryCollect(); err == nil || result != nil {
+		t.Errorf("expected the decoding error to reach TryCollect through Prefetch, got %v and %v", result, err)
+	}
+}
+
+func Test_DecodeJSON_EncodeJSON(t *testing.T) {
+	out := new(strings.Builder)
+	it := iterator.DecodeJSON[reading](strings.NewReader(`[{"sensor":"a","value":1},{"sensor":"b","value":-1}]`))
+	if err := iterator.EncodeJSON(it.Filter(func(r reading) bool {
+		return r.Value > 0
+	}), out); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if expected := "[{\"sensor\":\"a\",\"value\":1}\n]"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,96 @@
+package iterator
+
+// Operation is a step that can be chained to an iterator, defined once as a value so the same pipeline can be applied to
+// many iterators using the Apply method. Operations are created by functions such as MapOp and FilterOp, but any function
+// chaining operations to the iterator it's given, such as one calling Apply itself, can be converted to an Operation.
+type Operation[T any] func(Of[T]) Of[T]
+
+// MapOp returns an operation that chains a Map using the given function.
+func MapOp[T any](fn func(T) T) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.Map(fn)
+	}
+}
+
+// FilterOp returns an operation that chains a Filter using the given function.
+func FilterOp[T any](fn func(T) bool) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.Filter(fn)
+	}
+}
+
+// TryMapOp returns an operation that chains a TryMap using the given function.
+func TryMapOp[T any](fn func(T) (T, error)) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.TryMap(fn)
+	}
+}
+
+// TryFilterOp returns an operation that chains a TryFilter using the given function.
+func TryFilterOp[T any](fn func(T) (bool, error)) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.TryFilter(fn)
+	}
+}
+
+// MapConcurrentOp returns an operation that chains a MapConcurrent using the given function and concurrency.
+func MapConcurrentOp[T any](fn func(T) T, concurrency int) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.MapConcurrent(fn, concurrency)
+	}
+}
+
+// UniqueOp returns an operation that chains a Unique using the given options. Each iterator the operation is applied to
+// keeps track of the values it has seen separately.
+func UniqueOp[T any](opts ...UniqueOption) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.Unique(opts...)
+	}
+}
+
+// SortOp returns an operation that chains a Sort using the given function.
+func SortOp[T any](less func(a, b T) bool) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.Sort(less)
+	}
+}
+
+// ShuffleOp returns an operation that chains a Shuffle using the given options.
+func ShuffleOp[T any](opts ...ShuffleOption) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		return it.Shuffle(opts...)
+	}
+}
+
+func (it *iter[T]) Apply(ops ...Operation[T]) Of[T] {
+	var applied Of[T] = it
+	for _, op := range ops {
+		applied = op(applied)
+	}
+	return applied
+}
+
+// Named returns an operation that chains the given operation, naming the operations it chains so that they can be told
+// apart in the output of the Trace option and the statistics reported by the Instrument option. Naming an operation has no
+// other effect. Operations chained to iterators not created by this package, and operations returning a new iterator, are
+// chained without a name.
+func Named[T any](name string, op Operation[T]) Operation[T] {
+	return func(it Of[T]) Of[T] {
+		i, ok := it.(*iter[T])
+		if !ok {
+			return op(it)
+		}
+		first := i.pipeline().stages()
+		if result := op(it); result != it { // the operation created a new iterator, whose stages are numbered differently
+			return result
+		}
+		return i.chain(func(p *pipeline[T]) {
+			if p.names == nil {
+				p.names = make(map[int]string)
+			}
+			for idx := first; idx < p.stages(); idx++ {
+				p.names[idx] = name
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package iterator_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Apply(t *testing.T) {
+	cleanup := []iterator.Operation[string]{
+		iterator.MapOp(strings.TrimSpace),
+		iterator.FilterOp(func(val string) bool {
+			return val != ""
+		}),
+		iterator.MapOp(strings.ToLower),
+		iterator.UniqueOp[string](),
+		iterator.SortOp(func(a, b string) bool {
+			return a < b
+		}),
+	}
+	tests := map[string]struct {
+		source   []string
+		expected []string
+	}{
+		"names": {[]string{" Bob", "alice ", "", "BOB", "  "}, []string{"alice", "bob"}},
+		"tags":  {[]string{"go", "Rust", " GO "}, []string{"go", "rust"}},
+		"empty": {[]string{}, []string{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.From(test.source).Apply(cleanup...).Collect()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_Apply_TryOperations(t *testing.T) {
+	errNegative := errors.New("negative")
+	ops := []iterator.Operation[int]{
+		iterator.TryMapOp(func(val int) (int, error) {
+			if val < 0 {
+				return 0, errNegative
+			}
+			return val * 2, nil
+		}),
+		iterator.TryFilterOp(func(val int) (bool, error) {
+			return val > 2, nil
+		}),
+		iterator.MapConcurrentOp(func(val int) int {
+			return val + 1
+		}, 2),
+	}
+	if result, err := iterator.FromValues(1, 2, 3).Apply(ops...).TryCollect(); err != nil || !reflect.DeepEqual(result, []int{5, 7}) {
+		t.Errorf("expected [5 7], got %v and %v", result, err)
+	}
+	if result, err := iterator.FromValues(1, -2, 3).Apply(ops...).TryCollect(); !errors.Is(err, errNegative) || result != nil {
+		t.Errorf("expected error %v, got %v and %v", errNegative, result, err)
+	}
+}
+
+func Test_Apply_Custom(t *testing.T) {
+	evens := iterator.Operation[int](func(it iterator.Of[int]) iterator.Of[int] {
+		return it.Filter(func(val int) bool {
+			return val%2 == 0
+		})
+	})
+	shuffled := iterator.FromValues(1, 2, 3, 4).Apply(evens, iterator.ShuffleOp[int](iterator.ShuffleSeed(1)))
+	if result := iterator.SortOrdered(shuffled).Collect(); !reflect.DeepEqual(result, []int{2, 4}) {
+		t.Errorf("expected [2 4], got %v", result)
+	}
+}
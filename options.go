@@ -1,10 +1,30 @@
 package iterator
 
+import (
+	"context"
+	"io"
+	"math/rand"
+	"time"
+)
+
 // fromOptions is a struct that holds the options for creating an iterator using the From function.
 type fromOptions struct {
-	copySource bool // whether to copy the source slice when creating the iterator
-	threadSafe bool // whether to use a mutex when making calls to the Next method
-	bufferLen  int  // the initial capacity of the operations buffer
+	copySource    bool                       // whether to copy the source slice when creating the iterator
+	threadSafe    bool                       // whether to use a mutex when making calls to the Next method
+	bufferLen     int                        // the initial capacity of the operations buffer
+	parallel      bool                       // whether to apply the operations on multiple goroutines when collecting
+	workers       int                        // the number of goroutines used when collecting in parallel
+	chunkSize     int                        // the number of elements processed at a time by each goroutine when collecting in parallel. Less than 1 means automatic.
+	workStealing  bool                       // whether idle workers should steal work from busy ones when collecting in parallel
+	rand          *rand.Rand                 // the source of randomness used by random operations such as Shuffle and Sample
+	onStop        func()                     // the function called when the Stop method is called
+	yield         float64                    // the expected fraction of the source left after the chained operations, used to size the collected slice. 0 means the whole source.
+	collectFunc   any                        // the func(*iter[T], []T) []T used by the Collect method when a parallel execution option is used. Stored as any because the options aren't generic.
+	prefetch      int                        // the number of elements read ahead of the consumer on a separate goroutine, or 0 to read them on demand
+	instrument    func(OpStats)              // the function the statistics of each operation are reported to after a terminal operation
+	trace         io.Writer                  // where each element's journey through the operations is written during a terminal operation
+	onProgress    func(processed, total int) // the function told how many elements have been read from the source, every progressEvery elements
+	progressEvery int                        // the number of elements read from the source between calls to onProgress
 }
 
 // FromOption is a function that configures the parameters when creating an iterator using the From function.
@@ -17,8 +37,12 @@ func CopySource(shouldCopy bool) FromOption {
 	}
 }
 
-// ThreadSafe returns an option that specifies whether the iterator should be thread-safe when making calls to the Next method.
-// Note that this option incurs a performance penalty, as it requires the use of a mutex.
+// ThreadSafe returns an option that specifies whether the iterator should be thread-safe, so that its methods can be called
+// from multiple goroutines at once. Calls to Next are serialized, so each element is returned to exactly one caller, and
+// concurrent collections share the remaining elements between them. Operations such as Map and Filter can be chained while
+// other goroutines are collecting: a collection keeps using the operations that were chained when it started. The
+// functions passed to the operations must be safe for concurrent use themselves. Note that this option incurs a
+// performance penalty, as it requires the use of a mutex.
 func ThreadSafe(shouldLock bool) FromOption {
 	return func(opts *fromOptions) {
 		opts.threadSafe = shouldLock
@@ -34,9 +58,144 @@ func BufferLen(bufferLen int) FromOption {
 	}
 }
 
+// WithRand returns an option that specifies the source of randomness used by every random operation on the iterator, such
+// as Shuffle and Sample, so that pipelines using randomness are reproducible in tests and debugging sessions. Options
+// passed to a specific operation, like ShuffleRand, take precedence. Note that a *rand.Rand is not safe for concurrent use,
+// so it shouldn't be shared between iterators that are used concurrently.
+func WithRand(r *rand.Rand) FromOption {
+	return func(opts *fromOptions) {
+		opts.rand = r
+	}
+}
+
+// Parallel returns an option that makes the Collect method split the source into chunks and apply the chained operations
+// on multiple goroutines, dealing the chunks out to the workers round-robin. If workers is less than 1,
+// runtime.GOMAXPROCS(0) workers are used. The collected slice is always in source order. The functions passed to Map and
+// Filter must be safe for concurrent use. If the pipeline contains an operation that keeps state between elements, such
+// as Unique, the iterator is collected sequentially.
+// Operations chained after a Sort are applied sequentially, once everything before it has been processed in parallel.
+// This option is ignored if the PartitionedParallel option is also used.
+func Parallel(workers int) FromOption {
+	return func(opts *fromOptions) {
+		opts.parallel = true
+		opts.workers = workers
+	}
+}
+
+// ChunkSize returns an option that specifies how many elements each worker processes at a time when collecting in parallel.
+// By default, or if size is less than 1, the chunk size is chosen automatically: chunks hold about 16KiB worth of elements,
+// so they fit in a core's cache, but are shrunk as needed so that every worker gets at least four of them, down to a minimum
+// of 64 elements when work stealing is enabled. No more workers are started than there are chunks. This option has no
+// effect unless the Parallel option is also used.
+func ChunkSize(size int) FromOption {
+	return func(opts *fromOptions) {
+		opts.chunkSize = size
+	}
+}
+
+// WorkStealing returns an option that specifies whether idle workers should steal work from busy ones when collecting in
+// parallel. Each worker starts with its own chunk of the source, but processes it in small sub-ranges; once a worker runs
+// out of work, it takes the back half of the largest remaining range. This keeps every worker busy when the cost of
+// processing each element is highly skewed, at the price of some extra synchronization. Workers take one chunk of elements
+// at a time from their range, as configured by the ChunkSize option. This option has no effect unless the Parallel option
+// is also used.
+func WorkStealing(shouldSteal bool) FromOption {
+	return func(opts *fromOptions) {
+		opts.workStealing = shouldSteal
+	}
+}
+
+// ExpectedYield returns an option that specifies the fraction of the source expected to be left after the chained
+// operations, so that Collect and TryCollect allocate a slice of about that fraction of the source's length up front
+// instead of one as long as the source. This saves memory when a selective Filter keeps few elements of a large source;
+// if more are left, the slice grows as with append. Values outside of (0, 1] are ignored.
+func ExpectedYield(fraction float64) FromOption {
+	return func(opts *fromOptions) {
+		opts.yield = fraction
+	}
+}
+
+// Prefetch returns an option that makes sources that can't be rewound, such as FromFunc, FromChannel, FromSeq, and
+// FromRows, read up to n elements ahead of the consumer on a separate goroutine, so that the source's latency overlaps
+// with the processing of the elements already read. The goroutine is started by the first call to Next, and is released
+// by the Stop method or once the source is exhausted. As elements are read ahead, the source function is called on that
+// goroutine and may be called up to n times more than the number of elements consumed. Sources over slices and ranges
+// ignore the option, as do sources given an n less than 1.
+func Prefetch(n int) FromOption {
+	return func(opts *fromOptions) {
+		opts.prefetch = n
+	}
+}
+
+// OnStop returns an option that registers a function to be called the first time the Stop method is called, so that a
+// producer feeding a source such as FromFunc or FromChannel can be told to stop once the iterator is abandoned, for
+// example by cancelling the context the producer watches. The function isn't called if Stop never is.
+func OnStop(fn func()) FromOption {
+	return func(opts *fromOptions) {
+		opts.onStop = fn
+	}
+}
+
+// Instrument returns an option that reports what each of the chained operations did once a terminal operation, such as
+// Collect, TryCollect, or Seq, has finished with the elements it needs: hook is called once per operation, in the order they
+// were chained, with the number of elements the operation received and passed on and the time spent in it. This makes it
+// possible to find which stage of a long pipeline is slow or drops the most elements without wrapping each function.
+// Elements filtered out by an operation never reach the ones after it, so their In counts are lower. Timing every
+// element has a cost of its own, so the option is meant for diagnosing pipelines rather than for constant use.
+func Instrument(hook func(OpStats)) FromOption {
+	return func(opts *fromOptions) {
+		opts.instrument = hook
+	}
+}
+
+// Trace returns an option that writes each element's journey through the chained operations to w while a terminal
+// operation, such as Collect, TryCollect, or Seq, runs: a line for every operation an element reaches, showing its value
+// before and after the operation, or that it was filtered out or failed, and a line for each operation that needs every
+// element at once, such as Sort, showing how many went in and out. Operations are referred to by their position in the
+// pipeline, along with the name given to them using Named, if any. This makes it easy to see why a value is missing from
+// the result. Errors writing to w are ignored. As every element produces several lines, the option is meant for
+// debugging small inputs.
+func Trace(w io.Writer) FromOption {
+	return func(opts *fromOptions) {
+		opts.trace = w
+	}
+}
+
+// OnProgress returns an option that calls fn every time another every elements have been read from the source, and once
+// more when the source is exhausted, so long-running collections can drive progress bars or liveness logs. fn is passed the
+// number of elements read so far and the number of elements in the source, or -1 if that isn't known, as is the case for
+// sources such as FromFunc. Elements are counted as they are read, before the chained operations are applied. The count
+// is restarted by Reset, so every pass over a rewound source is reported from zero, and never exceeds the number of
+// elements in the source. fn is called on the goroutine reading the element, while holding the iterator's lock if the
+// ThreadSafe option is used. If every is less than 1, fn is called for every element.
+func OnProgress(every int, fn func(processed, total int)) FromOption {
+	return func(opts *fromOptions) {
+		opts.onProgress = fn
+		opts.progressEvery = every
+	}
+}
+
+// Options returns an option that applies each of the given options in order, so that a set of options can be defined once
+// and reused, for example as a package-level variable shared by every call site in a codebase. Options passed after it
+// to From override the ones it contains:
+//
+//	var Streaming = iterator.Options(iterator.ThreadSafe(true), iterator.BufferLen(8))
+//
+//	it := iterator.From(events, Streaming, iterator.BufferLen(16))
+func Options(opts ...FromOption) FromOption {
+	return func(options *fromOptions) {
+		for _, opt := range opts {
+			opt(options)
+		}
+	}
+}
+
 // uniqueOptions is a struct that holds the conditions for the Unique method.
 type uniqueOptions struct {
-	deref bool // whether to dereference pointers before evaluating uniqueness
+	deref      bool // whether to dereference pointers before evaluating uniqueness
+	keyFunc    any  // the func(T) any returning the value uniqueness is evaluated on. Stored as any because the options aren't generic.
+	maxEntries int  // the maximum number of values remembered, or 0 to remember every value
+	keepLast   bool // whether the last occurrence of each value is kept instead of the first
 }
 
 // UniqueOption is a function that configures the conditions for the Unique method.
@@ -52,8 +211,115 @@ func DerefPointers(shouldDeref bool) UniqueOption {
 	}
 }
 
+// MaxEntries returns a UniqueOption that bounds the memory used by Unique on very large streams by only remembering the n
+// most recently seen values, forgetting the least recently seen one when a new value arrives. Unique is no longer exact
+// with this option: a duplicate is only dropped if its value was seen among the last n distinct values, so duplicates
+// further apart than that are kept. Values are never dropped wrongly, though. This suits streams where duplicates arrive
+// close together, such as retried messages. If n is less than 1, every value is remembered, which is the default.
+func MaxEntries(n int) UniqueOption {
+	return func(opts *uniqueOptions) {
+		opts.maxEntries = n
+	}
+}
+
+// KeepLast returns a UniqueOption that specifies whether the last occurrence of each value should be kept instead of the
+// first, in the position it appears in, which suits sources where later records supersede earlier ones, such as event
+// upserts. As the last occurrence isn't known until every value has been seen, Unique has to buffer every value that
+// survived the operations chained before it, like Sort does, and the operations chained after it are applied to its
+// output.
+func KeepLast(shouldKeepLast bool) UniqueOption {
+	return func(opts *uniqueOptions) {
+		opts.keepLast = shouldKeepLast
+	}
+}
+
+// WithKeyFunc returns a UniqueOption that evaluates uniqueness on the key returned by the given function instead of on the
+// values themselves, so values are duplicates if their keys are equal. This makes it possible to compare pointers by the
+// values they point to, or structs by a subset of their fields, without the reflection used by DerefPointers, which this
+// option takes precedence over. Unique panics if the element type of the function doesn't match that of the iterator.
+func WithKeyFunc[T any, K comparable](fn func(T) K) UniqueOption {
+	return func(opts *uniqueOptions) {
+		opts.keyFunc = func(val T) any {
+			return fn(val)
+		}
+	}
+}
+
+// shuffleOptions is a struct that holds the conditions for the Shuffle method.
+type shuffleOptions struct {
+	rand *rand.Rand // the source of randomness used to shuffle the values. The global source is used if nil.
+}
+
+// ShuffleOption is a function that configures the conditions for the Shuffle method.
+type ShuffleOption func(*shuffleOptions)
+
+// ShuffleRand returns a ShuffleOption that specifies the source of randomness used to shuffle the values. This is useful for
+// deterministic tests. Note that a *rand.Rand is not safe for concurrent use, so it shouldn't be shared between iterators
+// that are collected concurrently.
+func ShuffleRand(r *rand.Rand) ShuffleOption {
+	return func(opts *shuffleOptions) {
+		opts.rand = r
+	}
+}
+
+// ShuffleSeed returns a ShuffleOption that shuffles the values using a new source of randomness seeded with the given value,
+// so the same seed always produces the same order for the same input.
+func ShuffleSeed(seed int64) ShuffleOption {
+	return func(opts *shuffleOptions) {
+		opts.rand = rand.New(rand.NewSource(seed))
+	}
+}
+
+// convertOptions is a struct that holds the conditions for conversions between element types, such as BytesToStrings.
+type convertOptions struct {
+	zeroCopy bool // whether to reuse the underlying memory of each value instead of copying it
+}
+
+// ConvertOption is a function that configures the conditions for conversions between element types, such as BytesToStrings.
+type ConvertOption func(*convertOptions)
+
+// ZeroCopy returns a ConvertOption that specifies whether conversions between []byte and string should reuse the memory of
+// each value instead of copying it, which removes an allocation per element. This is unsafe unless the pipeline is
+// read-only: a []byte converted to a string must not be modified afterwards, as strings are assumed to be immutable, and a
+// []byte converted from a string must never be written to at all, as it may point to read-only memory.
+func ZeroCopy(unsafeReuse bool) ConvertOption {
+	return func(opts *convertOptions) {
+		opts.zeroCopy = unsafeReuse
+	}
+}
+
+// DuplicatePolicy determines what happens when building a map and more than one value produces the same key.
+type DuplicatePolicy int
+
+const (
+	DuplicatesOverwrite DuplicatePolicy = iota // later values overwrite earlier ones with the same key
+	DuplicatesError                            // duplicated keys are reported as an error
+	DuplicatesKeepFirst                        // earlier values are kept, and later ones with the same key are ignored
+)
+
+// mapOptions is a struct that holds the conditions for building maps, such as with CollectMap.
+type mapOptions struct {
+	duplicates DuplicatePolicy // what to do when more than one value produces the same key
+}
+
+// MapOption is a function that configures the conditions for building maps, such as with CollectMap.
+type MapOption func(*mapOptions)
+
+// Duplicates returns a MapOption that specifies what happens when more than one value produces the same key. The default
+// is DuplicatesOverwrite.
+func Duplicates(policy DuplicatePolicy) MapOption {
+	return func(opts *mapOptions) {
+		opts.duplicates = policy
+	}
+}
+
 type intoChannelOptions struct {
-	closeChannel bool // whether to close the channel when the iterator is exhausted
+	closeChannel bool            // whether to close the channel when the iterator is exhausted
+	ctx          context.Context // the context whose cancellation stops the goroutine sending to the channel
+	buffer       int             // the buffer size of the channel created by Channel and CollectChannel, or -1 for the size of the source
+	partitionKey any             // the func(T) any returning the key FanOut partitions values by, or nil to deal them out round-robin. Stored as any because the options aren't generic.
+	interval     time.Duration   // the minimum time between values sent, or 0 to send them as fast as they are received
+	nextSend     time.Time       // the earliest time the next value can be sent when throttled
 }
 
 // IntoChannelOption is a function that configures the conditions for the IntoChannel method.
@@ -65,3 +331,63 @@ func CloseChannel(shouldClose bool) IntoChannelOption {
 		opts.closeChannel = shouldClose
 	}
 }
+
+// ChannelBuffer returns an IntoChannelOption that sets the buffer size of the channel created by Channel and
+// CollectChannel, or of each of the channels created by FanOut. By default the buffer can hold the whole source, split
+// evenly between the channels for FanOut, which is the fastest option for small slices but means the sending goroutine
+// never waits for a slow receiver. With a small buffer, the sending goroutine blocks once a receiver falls behind by n
+// values. A size of 0 makes the channel unbuffered, and a negative size restores the default. The option has no effect on
+// IntoChannel and CollectIntoChannel, as the caller creates the channel.
+func ChannelBuffer(n int) IntoChannelOption {
+	return func(opts *intoChannelOptions) {
+		opts.buffer = maxInt(n, -1)
+	}
+}
+
+// PartitionKey returns an IntoChannelOption that makes FanOut send all of the values with the same key, as returned by the
+// given function, to the same channel, instead of dealing values out round-robin. This is the usual requirement for
+// per-entity processing by a pool of workers. Keys are assigned to channels in the order they are first found, so the
+// channels stay balanced as long as the values are spread evenly across keys. FanOut panics if the element type of the
+// function doesn't match that of the iterator. The option has no effect on the other channel methods.
+func PartitionKey[T any, K comparable](fn func(T) K) IntoChannelOption {
+	return func(opts *intoChannelOptions) {
+		opts.partitionKey = func(val T) any {
+			return fn(val)
+		}
+	}
+}
+
+// Throttle returns an IntoChannelOption that sends at most one value per interval, such as one every 20ms to stay under
+// 50 requests per second when the receiver calls a rate-limited API. The first value is sent straight away, and each value
+// after it waits until the interval has passed since the previous one was sent; waiting for a slow receiver counts
+// towards the interval. Broadcast sends each value to all of its channels at once, and FanOut throttles the values across
+// all of its channels, not per channel. An interval of 0 or less turns throttling off.
+func Throttle(interval time.Duration) IntoChannelOption {
+	return func(opts *intoChannelOptions) {
+		opts.interval = interval
+	}
+}
+
+// WithContext returns an IntoChannelOption that stops the goroutine sending to the channel once the given context is
+// cancelled, so it doesn't leak if the receiver stops reading before the iterator is exhausted. The rest of the iterator
+// is left unconsumed, and the channel is still closed if the CloseChannel option is used.
+func WithContext(ctx context.Context) IntoChannelOption {
+	return func(opts *intoChannelOptions) {
+		opts.ctx = ctx
+	}
+}
+
+type resetOptions struct {
+	clearOperations bool // whether to remove the chained operations when resetting
+}
+
+// ResetOption is a function that configures the Reset method.
+type ResetOption func(*resetOptions)
+
+// ClearOperations returns a ResetOption that specifies whether the chained operations, such as Map and Filter, should be
+// removed when resetting, so that the iterator can be reused with a new pipeline as if it had just been created.
+func ClearOperations(shouldClear bool) ResetOption {
+	return func(opts *resetOptions) {
+		opts.clearOperations = shouldClear
+	}
+}
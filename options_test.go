@@ -52,3 +52,76 @@ func Test_CloseChannel(t *testing.T) {
 		t.Error("Expected true, got false")
 	}
 }
+
+func Test_Options(t *testing.T) {
+	preset := Options(ThreadSafe(true), BufferLen(8), Parallel(4))
+	fromOpts := new(fromOptions)
+	for _, opt := range []FromOption{preset, BufferLen(16)} {
+		opt(fromOpts)
+	}
+	if !fromOpts.threadSafe || !fromOpts.parallel || fromOpts.workers != 4 {
+		t.Errorf("Expected the preset to be applied, got %+v", fromOpts)
+	}
+	if fromOpts.bufferLen != 16 {
+		t.Errorf("Expected later options to override the preset, got a buffer length of %d", fromOpts.bufferLen)
+	}
+}
+
+func Test_ExpectedYield(t *testing.T) {
+	source := make([]int, 1000)
+	tests := map[string]struct {
+		fraction float64
+		expected int
+	}{
+		"selective":  {0.01, 10},
+		"rounded_up": {0.0001, 1},
+		"ignored":    {1.5, 1000},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := From(source, ExpectedYield(test.fraction)).Filter(func(int) bool {
+				return false
+			}).Collect()
+			if cap(result) != test.expected {
+				t.Errorf("Expected a capacity of %d, got %d", test.expected, cap(result))
+			}
+		})
+	}
+}
+
+// need to enable the race detector for this test to really be valuable
+func Test_ThreadSafe_Pipeline(t *testing.T) {
+	source := make([]int, 1000)
+	for i := range source {
+		source[i] = i % 100
+	}
+	it := From(source, ThreadSafe(true)).Unique()
+	wg := sync.WaitGroup{}
+	results := make([][]int, 4)
+	for i := range results {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			results[i] = it.Collect()
+		}()
+		go func() {
+			defer wg.Done()
+			it.Filter(func(int) bool {
+				return true
+			}).Map(func(val int) int {
+				return val
+			})
+		}()
+	}
+	wg.Wait()
+	total := 0
+	for _, result := range results {
+		total += len(result)
+	}
+	if total != 100 {
+		t.Errorf("Expected 100 unique values between the collections, got %d", total)
+	}
+	if n := len(it.(*iter[int]).pipeline().operations); n != 9 {
+		t.Errorf("Expected 9 operations to be chained, got %d", n)
+	}
+}
@@ -0,0 +1,300 @@
+package iterator
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	chunkBytes      = 16 << 10 // the number of bytes of elements in an automatically sized chunk, half of a typical L1 data cache
+	chunksPerWorker = 4        // the minimum number of automatically sized chunks each worker should get
+	minStealChunk   = 64       // the minimum automatic chunk size when work stealing, so idle workers don't spend more time looking for work than doing it
+)
+
+// PartitionedParallel returns an option that makes the Collect method apply the chained operations on multiple
+// goroutines. Elements are partitioned using the given key function: all elements with the same key are processed by the
+// same worker in the order they appear in the source, while elements with different keys are processed concurrently.
+// This is the usual requirement for per-entity event processing. If workers is less than 1, runtime.GOMAXPROCS(0)
+// workers are used. The collected slice is always in source order, regardless of which worker processed each element.
+//
+// The functions passed to Map and Filter must be safe for concurrent use. If the pipeline contains an operation that keeps
+// state between elements, such as Unique, the iterator is collected sequentially instead. Operations chained after a Sort
+// are applied sequentially, once everything before it has been processed in parallel.
+//
+// From panics if the element type of the key function doesn't match the element type of the source. If the Parallel
+// option is also used, PartitionedParallel takes precedence regardless of the order the options are passed in.
+func PartitionedParallel[T any, K comparable](key func(T) K, workers int) FromOption {
+	return func(opts *fromOptions) {
+		opts.collectFunc = func(it *iter[T], dst []T) []T {
+			return partitionedCollect(it, dst, key, workers)
+		}
+	}
+}
+
+func (it *iter[T]) MapConcurrent(fn func(T) T, concurrency int) Of[T] {
+	return it.chain(func(p *pipeline[T]) {
+		p.addBarrier(func(vals []T) []T {
+			mapConcurrent(vals, fn, concurrency)
+			return vals
+		})
+		p.preserving++
+	})
+}
+
+// mapConcurrent replaces each of the given values with the result of calling fn on it, with up to concurrency calls in
+// flight at a time. Each goroutine takes the next unprocessed index as soon as its previous call returns, so one slow call
+// doesn't hold up the others.
+func mapConcurrent[T any](vals []T, fn func(T) T, concurrency int) {
+	concurrency = minInt(workerCount(concurrency), len(vals))
+	next := int64(-1)
+	wg := sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := int(atomic.AddInt64(&next, 1)); idx < len(vals); idx = int(atomic.AddInt64(&next, 1)) {
+				vals[idx] = fn(vals[idx])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func partitionedCollect[T any, K comparable](it *iter[T], dst []T, key func(T) K, workers int) []T {
+	p := it.pipeline()
+	if p.sequential {
+		return collect(it, dst)
+	}
+	p, report := it.instrumented(p)
+	defer report()
+	pending := it.drain()
+	workers = minInt(workerCount(workers), len(pending))
+	results := make([]maybe[T], len(pending)) // each worker only writes to the indexes it receives, so no locking is needed
+	queues := make([]chan int, workers)
+	wg := sync.WaitGroup{}
+	for i := range queues {
+		queues[i] = make(chan int, 64)
+		wg.Add(1)
+		go func(queue <-chan int) {
+			defer wg.Done()
+			for idx := range queue {
+				p.applyRange(pending, results, idx, idx+1)
+			}
+		}(queues[i])
+	}
+	assigned := make(map[K]int) // the worker each key has been assigned to
+	for idx, val := range pending {
+		k := key(val)
+		worker, ok := assigned[k]
+		if !ok {
+			worker = len(assigned) % workers
+			assigned[k] = worker
+		}
+		queues[worker] <- idx
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+	return flushCompacted(p, dst, results)
+}
+
+func chunkedCollect[T any](it *iter[T], dst []T, workers, chunkSize int, workStealing bool) []T {
+	p := it.pipeline()
+	if p.sequential {
+		return collect(it, dst)
+	}
+	p, report := it.instrumented(p)
+	defer report()
+	pending := it.drain()
+	workers = workerCount(workers)
+	if chunkSize < 1 {
+		chunkSize = autoChunkSize(unsafe.Sizeof(*new(T)), len(pending), workers)
+		if workStealing && chunkSize < minStealChunk {
+			chunkSize = minStealChunk
+		}
+	}
+	chunks := (len(pending) + chunkSize - 1) / chunkSize
+	workers = minInt(workers, chunks) // no point in starting workers that couldn't fill a single chunk
+	results := make([]maybe[T], len(pending))
+	if workers <= 1 {
+		p.applyRange(pending, results, 0, len(pending))
+		return flushCompacted(p, dst, results)
+	}
+	wg := sync.WaitGroup{}
+	if !workStealing {
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(first int) {
+				defer wg.Done()
+				for chunk := first; chunk < chunks; chunk += workers { // chunks are dealt out round-robin
+					p.applyRange(pending, results, chunk*chunkSize, minInt((chunk+1)*chunkSize, len(pending)))
+				}
+			}(w)
+		}
+		wg.Wait()
+		return flushCompacted(p, dst, results)
+	}
+	spans := make([]*span, workers)
+	spanLen := (len(pending) + workers - 1) / workers
+	unclaimed := int64(len(pending))
+	for i := range spans {
+		spans[i] = newSpan(minInt(i*spanLen, len(pending)), minInt((i+1)*spanLen, len(pending)), &unclaimed)
+	}
+	for i := range spans {
+		wg.Add(1)
+		go func(own *span) {
+			defer wg.Done()
+			for {
+				for lo, hi, ok := own.take(chunkSize); ok; lo, hi, ok = own.take(chunkSize) {
+					p.applyRange(pending, results, lo, hi)
+				}
+				if !own.stealFrom(spans) {
+					return
+				}
+			}
+		}(spans[i])
+	}
+	wg.Wait()
+	return flushCompacted(p, dst, results)
+}
+
+// autoChunkSize picks a chunk size for the given element size, number of elements, and number of workers. Chunks are sized
+// to fit comfortably in a core's L1 data cache, but are kept small enough that every worker gets several of them, so that
+// a slow chunk doesn't hold up the whole collection.
+func autoChunkSize(elemSize uintptr, elems, workers int) int {
+	if elemSize == 0 {
+		elemSize = 1
+	}
+	size := int(chunkBytes / elemSize)
+	if perWorker := (elems + workers*chunksPerWorker - 1) / (workers * chunksPerWorker); perWorker < size {
+		size = perWorker
+	}
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// span is the range of source indexes a worker has yet to process. The bounds are only changed while holding the mutex,
+// but are read atomically so that idle workers can look for a victim without locking every span.
+type span struct {
+	mu        sync.Mutex
+	next      int64  // the next index to be processed
+	end       int64  // the index after the last one to be processed
+	unclaimed *int64 // the number of indexes left in all of the spans, shared between them so idle workers can stop without scanning
+}
+
+func newSpan(next, end int, unclaimed *int64) *span {
+	return &span{next: int64(next), end: int64(end), unclaimed: unclaimed}
+}
+
+// take removes up to n indexes from the front of the span, returning the removed range and whether there was anything left.
+func (s *span) take(n int) (lo, hi int, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, end := s.next, s.end
+	if next >= end {
+		return 0, 0, false
+	}
+	lo, hi = int(next), minInt(int(next)+n, int(end))
+	atomic.StoreInt64(&s.next, int64(hi))
+	atomic.AddInt64(s.unclaimed, int64(lo-hi))
+	return lo, hi, true
+}
+
+// remaining returns an estimate of the number of indexes left in the span, without locking it.
+func (s *span) remaining() int {
+	return int(atomic.LoadInt64(&s.end) - atomic.LoadInt64(&s.next))
+}
+
+// stealFrom moves the back half of the largest remaining span into s, which must be empty. It returns false if there was
+// nothing left to steal. The victim and s are never locked at the same time, so workers stealing concurrently can't deadlock.
+func (s *span) stealFrom(spans []*span) bool {
+	for atomic.LoadInt64(s.unclaimed) > 0 {
+		var victim *span
+		most := 0
+		for _, candidate := range spans {
+			if n := candidate.remaining(); n > most {
+				victim, most = candidate, n
+			}
+		}
+		if victim == nil {
+			return false
+		}
+		victim.mu.Lock()
+		next, end := victim.next, victim.end
+		if next >= end { // the owner finished its range in the meantime, look for another victim
+			victim.mu.Unlock()
+			continue
+		}
+		mid := next + (end-next)/2
+		atomic.StoreInt64(&victim.end, mid)
+		victim.mu.Unlock()
+
+		s.mu.Lock()
+		atomic.StoreInt64(&s.next, mid)
+		atomic.StoreInt64(&s.end, end)
+		s.mu.Unlock()
+		return true
+	}
+	return false
+}
+
+// drain consumes the rest of the source, returning the remaining elements without applying any operations.
+func (it *iter[T]) drain() []T {
+	pending := make([]T, 0, it.SizeHint())
+	it.ForEach(func(val T) {
+		pending = append(pending, val)
+	})
+	return pending
+}
+
+// applyRange applies the chained operations to the pending elements between lo and hi, storing the outcome in the
+// results at the same indexes.
+func (p *pipeline[T]) applyRange(pending []T, results []maybe[T], lo, hi int) {
+	for idx := lo; idx < hi; idx++ {
+		results[idx].reset(pending[idx])
+		p.apply(&results[idx])
+	}
+}
+
+// flushCompacted appends the results to dst, then runs the barriers on them.
+func flushCompacted[T any](p *pipeline[T], dst []T, results []maybe[T]) []T {
+	collected, _ := p.flush(compact(dst, results), len(dst)) // errors from Try operations are only reported by TryCollect
+	return collected
+}
+
+// compact appends the values of the given elements that weren't filtered out to dst, preserving their order.
+func compact[T any](dst []T, results []maybe[T]) []T {
+	for _, mb := range results {
+		if mb.ok {
+			dst = append(dst, mb.val)
+		}
+	}
+	return dst
+}
+
+// workerCount returns the given number of workers, or runtime.GOMAXPROCS(0) if it's less than 1.
+func workerCount(workers int) int {
+	if workers < 1 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
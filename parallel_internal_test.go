@@ -0,0 +1,66 @@
+package iterator
+
+import "testing"
+
+func Test_Span_Take(t *testing.T) {
+	unclaimed := int64(5)
+	s := newSpan(0, 5, &unclaimed)
+	if lo, hi, ok := s.take(3); !ok || lo != 0 || hi != 3 {
+		t.Errorf("Expected [0, 3), got [%d, %d)", lo, hi)
+	}
+	if lo, hi, ok := s.take(3); !ok || lo != 3 || hi != 5 {
+		t.Errorf("Expected [3, 5), got [%d, %d)", lo, hi)
+	}
+	if _, _, ok := s.take(3); ok {
+		t.Error("Expected the span to be empty")
+	}
+	if unclaimed != 0 {
+		t.Errorf("Expected nothing to be unclaimed, got %d", unclaimed)
+	}
+}
+
+func Test_Span_StealFrom(t *testing.T) {
+	unclaimed := int64(110)
+	small := newSpan(0, 10, &unclaimed)
+	large := newSpan(10, 110, &unclaimed)
+	thief := newSpan(110, 110, &unclaimed)
+	if !thief.stealFrom([]*span{small, large, thief}) {
+		t.Fatal("Expected the steal to succeed")
+	}
+	if thief.next != 60 || thief.end != 110 {
+		t.Errorf("Expected the thief to get [60, 110), got [%d, %d)", thief.next, thief.end)
+	}
+	if large.next != 10 || large.end != 60 {
+		t.Errorf("Expected the victim to keep [10, 60), got [%d, %d)", large.next, large.end)
+	}
+	if small.next != 0 || small.end != 10 {
+		t.Errorf("Expected the smaller span to be untouched, got [%d, %d)", small.next, small.end)
+	}
+	drained := int64(0)
+	thief = newSpan(0, 0, &drained)
+	if thief.stealFrom([]*span{newSpan(3, 3, &drained), newSpan(7, 7, &drained)}) {
+		t.Error("Expected nothing to steal")
+	}
+}
+
+func Test_AutoChunkSize(t *testing.T) {
+	tests := map[string]struct {
+		elemSize uintptr
+		elems    int
+		workers  int
+		expected int
+	}{
+		"cache bound":   {elemSize: 8, elems: 1_000_000, workers: 4, expected: 2048},
+		"large structs": {elemSize: 1024, elems: 1_000_000, workers: 4, expected: 16},
+		"few elements":  {elemSize: 8, elems: 1000, workers: 4, expected: 63},
+		"huge elements": {elemSize: 1 << 20, elems: 1000, workers: 4, expected: 1},
+		"empty struct":  {elemSize: 0, elems: 1_000_000, workers: 1, expected: 16384},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if size := autoChunkSize(test.elemSize, test.elems, test.workers); size != test.expected {
+				t.Errorf("Expected %d, got %d", test.expected, size)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package iterator_test
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_PartitionedParallel(t *testing.T) {
+	type event struct {
+		entity string
+		seq    int
+	}
+	source := make([]event, 0, 300)
+	for i := 0; i < 100; i++ {
+		for _, entity := range []string{"a", "b", "c"} {
+			source = append(source, event{entity, i})
+		}
+	}
+	mu := sync.Mutex{}
+	processed := make(map[string][]int)
+	it := iterator.From(source, iterator.PartitionedParallel(func(e event) string {
+		return e.entity
+	}, 3))
+	result := it.Map(func(e event) event {
+		mu.Lock()
+		processed[e.entity] = append(processed[e.entity], e.seq)
+		mu.Unlock()
+		return e
+	}).Filter(func(e event) bool {
+		return e.seq%2 == 0
+	}).Collect()
+	for entity, seqs := range processed {
+		for i, seq := range seqs {
+			if seq != i {
+				t.Fatalf("expected %s events to be processed in order, got %v", entity, seqs)
+			}
+		}
+	}
+	expected := iterator.From(source).Filter(func(e event) bool {
+		return e.seq%2 == 0
+	}).Collect()
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func Test_PartitionedParallel_Unique(t *testing.T) {
+	it := iterator.From([]int{1, 2, 3, 2, 1, 4}, iterator.PartitionedParallel(func(i int) int {
+		return i % 2
+	}, 2))
+	if result := it.Unique().Collect(); !reflect.DeepEqual(result, []int{1, 2, 3, 4}) {
+		t.Errorf("expected [1 2 3 4], got %v", result)
+	}
+}
+
+func Test_PartitionedParallel_MismatchedKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected From to panic")
+		}
+	}()
+	iterator.From([]int{1, 2, 3}, iterator.PartitionedParallel(func(s string) string {
+		return s
+	}, 2))
+}
+
+func Test_PartitionedParallel_Precedence(t *testing.T) {
+	mu := sync.Mutex{}
+	last := make(map[int]int) // the last value seen for each key, to prove keys were processed in order
+	it := iterator.From([]int{1, 2, 3, 4, 5, 6, 7, 8}, iterator.Parallel(4), iterator.PartitionedParallel(func(i int) int {
+		return i % 2
+	}, 4))
+	result := it.Map(func(val int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		if last[val%2] > val {
+			t.Errorf("expected %d to be processed after %d", last[val%2], val)
+		}
+		last[val%2] = val
+		return val
+	}).Collect()
+	if !reflect.DeepEqual(result, []int{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("expected [1 2 3 4 5 6 7 8], got %v", result)
+	}
+}
+
+func Test_Parallel(t *testing.T) {
+	source := make([]int, 10_000)
+	for i := range source {
+		source[i] = i
+	}
+	double := func(val int) int {
+		if val%1000 == 0 { // make a handful of elements much more expensive than the rest
+			for i := 0; i < 100_000; i++ {
+				val++
+			}
+			val -= 100_000
+		}
+		return val * 2
+	}
+	isDiv3 := func(val int) bool {
+		return val%3 == 0
+	}
+	expected := iterator.From(source).Map(double).Filter(isDiv3).Collect()
+	tests := map[string][]iterator.FromOption{
+		"chunked":        {iterator.Parallel(4)},
+		"work stealing":  {iterator.Parallel(4), iterator.WorkStealing(true)},
+		"default":        {iterator.Parallel(0)},
+		"single worker":  {iterator.Parallel(1), iterator.WorkStealing(true)},
+		"more than data": {iterator.Parallel(20_000), iterator.WorkStealing(true)},
+		"chunk size":     {iterator.Parallel(4), iterator.ChunkSize(7)},
+		"stealing chunk": {iterator.Parallel(4), iterator.ChunkSize(7), iterator.WorkStealing(true)},
+	}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.From(source, opts...).Map(double).Filter(isDiv3).Collect()
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("expected %+v, got %+v", expected, result)
+			}
+		})
+	}
+}
+
+func Test_Parallel_WorkStealing_Rebalances(t *testing.T) {
+	source := make([]int, 256) // two workers get 128 elements each, and take them 64 at a time
+	for i := range source {
+		source[i] = i
+	}
+	stolen := make(chan struct{})
+	it := iterator.From(source, iterator.Parallel(2), iterator.WorkStealing(true))
+	result := it.Map(func(val int) int {
+		switch val {
+		case 0: // the first worker is stuck until the back of its own chunk has been processed by someone else
+			select {
+			case <-stolen:
+			case <-time.After(5 * time.Second):
+				t.Error("expected the second worker to steal from the first one")
+			}
+		case 100:
+			close(stolen)
+		}
+		return val
+	}).Collect()
+	if !reflect.DeepEqual(result, source) {
+		t.Errorf("expected %+v, got %+v", source, result)
+	}
+}
+
+func Test_Parallel_Sort(t *testing.T) {
+	source := make([]int, 100)
+	for i := range source {
+		source[i] = i
+	}
+	reached := make(chan struct{})
+	isEven := func(val int) bool {
+		switch val {
+		case 0: // only returns if the other chunk is being processed at the same time
+			select {
+			case <-reached:
+			case <-time.After(5 * time.Second):
+				t.Error("expected the operations before Sort to run in parallel")
+			}
+		case 99:
+			close(reached)
+		}
+		return val%2 == 0
+	}
+	result := iterator.From(source, iterator.Parallel(2)).Filter(isEven).Sort(func(a, b int) bool {
+		return a > b
+	}).Map(func(val int) int {
+		return val / 2
+	}).Collect()
+	expected := make([]int, 0, 50)
+	for i := 49; i >= 0; i-- {
+		expected = append(expected, i)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func Test_Parallel_CollectInto(t *testing.T) {
+	for name, opt := range map[string]iterator.FromOption{
+		"chunked": iterator.Parallel(2),
+		"partitioned": iterator.PartitionedParallel(func(val int) bool {
+			return val%2 == 0
+		}, 2),
+	} {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.From([]int{4, 3, 2, 1}, opt).Filter(func(val int) bool {
+				return val > 1
+			}).Sort(func(a, b int) bool {
+				return a < b
+			}).CollectInto([]int{0})
+			if !reflect.DeepEqual(result, []int{0, 2, 3, 4}) {
+				t.Errorf("expected [0 2 3 4], got %v", result)
+			}
+		})
+	}
+}
+
+func Test_MapConcurrent(t *testing.T) {
+	var inFlight, most int64
+	result := iterator.Range(0, 20, 1).Filter(func(val int) bool {
+		return val%2 == 0
+	}).MapConcurrent(func(val int) int {
+		n := atomic.AddInt64(&inFlight, 1)
+		for m := atomic.LoadInt64(&most); n > m && !atomic.CompareAndSwapInt64(&most, m, n); m = atomic.LoadInt64(&most) {
+		}
+		time.Sleep(time.Duration(10-val/2) * time.Millisecond) // later values finish first
+		atomic.AddInt64(&inFlight, -1)
+		return val * 10
+	}, 4).Map(func(val int) int {
+		return val + 1
+	}).Collect()
+	expected := []int{1, 21, 41, 61, 81, 101, 121, 141, 161, 181}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+	if most < 2 || most > 4 {
+		t.Errorf("expected between 2 and 4 calls in flight at a time, got %d", most)
+	}
+}
@@ -0,0 +1,56 @@
+package iterator
+
+// Result is a value paired with the error that occurred while producing it, for sources that can fail one element at a
+// time, such as rows read from a database. Unlike TryMap, which stops TryCollect at the first error, an iterator of results
+// keeps going, so every failure can be handled or reported.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Ok returns a successful result holding the given value.
+func Ok[T any](val T) Result[T] {
+	return Result[T]{Value: val}
+}
+
+// Fail returns a failed result holding the given error.
+func Fail[T any](err error) Result[T] {
+	return Result[T]{Err: err}
+}
+
+// MapOk chains a Map operation that applies fn to the value of every successful result, turning it into a failed result if
+// fn returns an error. Failed results are passed through unchanged. The function is lazily evaluated, like Map.
+func MapOk[T any](it Of[Result[T]], fn func(T) (T, error)) Of[Result[T]] {
+	return it.Map(func(res Result[T]) Result[T] {
+		if res.Err != nil {
+			return res
+		}
+		val, err := fn(res.Value)
+		if err != nil {
+			return Fail[T](err)
+		}
+		return Ok(val)
+	})
+}
+
+// FilterErrors chains a Filter operation that drops every failed result, leaving only the successful ones.
+func FilterErrors[T any](it Of[Result[T]]) Of[Result[T]] {
+	return it.Filter(func(res Result[T]) bool {
+		return res.Err == nil
+	})
+}
+
+// Partition applies all of the chained operations to the iterator, then splits the results into the values of the
+// successful ones and the errors of the failed ones, each in the order they were produced.
+func Partition[T any](it Of[Result[T]]) ([]T, []error) {
+	var vals []T
+	var errs []error
+	for res := range it.Seq() {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		} else {
+			vals = append(vals, res.Value)
+		}
+	}
+	return vals, errs
+}
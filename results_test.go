@@ -0,0 +1,37 @@
+package iterator_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Results(t *testing.T) {
+	errRead := errors.New("read failed")
+	rows := []iterator.Result[string]{
+		iterator.Ok("1"),
+		iterator.Fail[string](errRead),
+		iterator.Ok("x"),
+		iterator.Ok("3"),
+	}
+	double := func(val string) (string, error) {
+		n, err := strconv.Atoi(val)
+		return strconv.Itoa(n * 2), err
+	}
+
+	vals, errs := iterator.Partition(iterator.MapOk(iterator.From(rows), double))
+	if !reflect.DeepEqual(vals, []string{"2", "6"}) {
+		t.Errorf("expected [2 6], got %v", vals)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], errRead) || !errors.Is(errs[1], strconv.ErrSyntax) {
+		t.Errorf("expected the read error followed by a syntax error, got %v", errs)
+	}
+
+	vals, errs = iterator.Partition(iterator.FilterErrors(iterator.From(rows)))
+	if !reflect.DeepEqual(vals, []string{"1", "x", "3"}) || errs != nil {
+		t.Errorf("expected [1 x 3] and no errors, got %v and %v", vals, errs)
+	}
+}
@@ -0,0 +1,52 @@
+package iterator
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryPolicy determines how WithRetry retries a source whose reads fail.
+type RetryPolicy struct {
+	MaxAttempts int              // the number of times each read is attempted before its error is reported, including the first. Less than 1 means a single attempt.
+	Backoff     time.Duration    // the delay before the first retry of a read, doubled before each retry after it
+	MaxBackoff  time.Duration    // the longest delay between retries, or 0 for no limit
+	Retryable   func(error) bool // reports whether an error is transient and worth retrying, or nil to retry every error
+}
+
+// WithRetry returns a new iterator that pulls its elements from the given function, which returns the next element and
+// whether there was one, as FromFunc does, or an error if the read failed. A failed read is retried as set by the policy,
+// waiting longer between each attempt, so transient failures of a remote source, such as a paginated API, don't end the
+// iteration. The function is expected to resume where it left off when called again after an error. If a read still fails
+// after the last attempt, or its error isn't retryable, the iteration ends there, and the error is returned by TryCollect
+// and TryForEach along with the index of the element and the number of attempts made; other methods only see the elements
+// before it. As with FromFunc, Reset has no effect on the elements returned. The options are the same as for From,
+// although CopySource has no effect.
+func WithRetry[T any](read func() (T, bool, error), policy RetryPolicy, opts ...FromOption) Of[T] {
+	attempts := maxInt(policy.MaxAttempts, 1)
+	done := false
+	return newIter(nil, func(it *iter[T]) (T, bool) {
+		if done {
+			return *new(T), false
+		}
+		backoff := policy.Backoff
+		for attempt := 1; ; attempt++ {
+			val, ok, err := read()
+			switch {
+			case err == nil && !ok:
+				done = true
+				return *new(T), false
+			case err == nil:
+				it.nextIndex++
+				return val, true
+			case attempt >= attempts || (policy.Retryable != nil && !policy.Retryable(err)):
+				done, it.err = true, fmt.Errorf("iterator: reading element %d after %d attempts: %w", it.nextIndex, attempt, err)
+				return *new(T), false
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+	}, 0, opts)
+}
@@ -0,0 +1,79 @@
+package iterator_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_WithRetry(t *testing.T) {
+	errTransient := errors.New("timeout")
+	errFatal := errors.New("not found")
+	tests := map[string]struct {
+		failures []error // the errors returned before each element is read successfully
+		policy   iterator.RetryPolicy
+		expected []int
+		err      error
+	}{
+		"no failures": {
+			failures: []error{nil, nil, nil},
+			policy:   iterator.RetryPolicy{MaxAttempts: 3},
+			expected: []int{1, 2, 3},
+		},
+		"transient failures": {
+			failures: []error{errTransient, nil, errTransient},
+			policy:   iterator.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+			expected: []int{1, 2, 3},
+		},
+		"attempts exhausted": {
+			failures: []error{nil, errTransient},
+			policy:   iterator.RetryPolicy{MaxAttempts: 1},
+			err:      errTransient,
+		},
+		"not retryable": {
+			failures: []error{errFatal},
+			policy: iterator.RetryPolicy{MaxAttempts: 5, Retryable: func(err error) bool {
+				return errors.Is(err, errTransient)
+			}},
+			err: errFatal,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			next, failed := 0, false
+			read := func() (int, bool, error) {
+				if next == len(test.failures) {
+					return 0, false, nil
+				}
+				if err := test.failures[next]; err != nil && !failed {
+					failed = true
+					return 0, false, err
+				}
+				next, failed = next+1, false
+				return next, true, nil
+			}
+			result, err := iterator.WithRetry(read, test.policy).TryCollect()
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected error %v, got %v", test.err, err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+
+	attempts := 0
+	_, err := iterator.WithRetry(func() (int, bool, error) {
+		attempts++
+		return 0, false, errTransient
+	}, iterator.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}).TryCollect()
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if expected := "iterator: reading element 0 after 3 attempts: timeout"; err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
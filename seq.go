@@ -0,0 +1,69 @@
+package iterator
+
+import goiter "iter" // aliased because the package already declares the iter type
+
+func (it *iter[T]) Seq() goiter.Seq[T] {
+	return func(yield func(T) bool) {
+		it.process(yield)
+	}
+}
+
+func (it *iter[T]) Seq2() goiter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		idx := 0
+		it.process(func(val T) bool {
+			defer func() { idx++ }()
+			return yield(idx, val)
+		})
+	}
+}
+
+// FromSeq returns a new iterator that pulls its elements from the given range-over-func sequence, such as those returned
+// by slices.Values or maps.Keys. The sequence is consumed lazily, one element per call to Next, so infinite sequences are
+// supported as long as the iterator is only consumed with methods that stop early. The sequence is suspended between
+// calls, and is only stopped once it's exhausted or the Stop method is called, so call Stop when abandoning the iterator
+// early to let the sequence run its deferred cleanup. As with FromFunc, Reset has no effect on the elements returned. The
+// options are the same as for From, although CopySource has no effect.
+func FromSeq[T any](seq goiter.Seq[T], opts ...FromOption) Of[T] {
+	var next func() (T, bool)
+	var stop func()
+	it := fromFunc(func() (T, bool) {
+		if next == nil { // start pulling lazily, so that a sequence that's never iterated over is never started
+			next, stop = goiter.Pull(seq)
+		}
+		val, ok := next()
+		if !ok {
+			stop()
+		}
+		return val, ok
+	}, opts)
+	it.onStop(func() {
+		if stop != nil {
+			stop()
+		}
+	})
+	return it
+}
+
+// FromSeq2 returns a new iterator over the pairs of the given range-over-func sequence as entries, such as those returned
+// by maps.All or slices.All. It is consumed the same way as the sequence passed to FromSeq.
+func FromSeq2[K comparable, V any](seq goiter.Seq2[K, V], opts ...FromOption) Of[Entry[K, V]] {
+	var next func() (K, V, bool)
+	var stop func()
+	it := fromFunc(func() (Entry[K, V], bool) {
+		if next == nil {
+			next, stop = goiter.Pull2(seq)
+		}
+		k, v, ok := next()
+		if !ok {
+			stop()
+		}
+		return Entry[K, V]{Key: k, Value: v}, ok
+	}, opts)
+	it.onStop(func() {
+		if stop != nil {
+			stop()
+		}
+	})
+	return it
+}
@@ -0,0 +1,105 @@
+package iterator_test
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Iterator_Seq(t *testing.T) {
+	it := iterator.From([]int{1, 2, 3, 4, 5, 6}).Filter(func(val int) bool {
+		return val%2 == 0
+	})
+	result := []int{}
+	for val := range it.Seq() {
+		result = append(result, val)
+	}
+	if !reflect.DeepEqual(result, []int{2, 4, 6}) {
+		t.Errorf("expected [2 4 6], got %v", result)
+	}
+}
+
+func Test_Iterator_Seq_Break(t *testing.T) {
+	it := iterator.Range(0, 10, 1)
+	for val := range it.Seq() {
+		if val == 3 {
+			break
+		}
+	}
+	if val, ok := it.Next(); !ok || val != 4 {
+		t.Errorf("expected the iteration to stop after 3, got %d", val)
+	}
+}
+
+func Test_FromSeq(t *testing.T) {
+	result := iterator.FromSeq(slices.Values([]string{"b", "a", "b"})).Unique().Collect()
+	if !reflect.DeepEqual(result, []string{"b", "a"}) {
+		t.Errorf("expected [b a], got %v", result)
+	}
+	keys := iterator.SortOrdered(iterator.FromSeq(maps.Keys(map[int]bool{3: true, 1: true, 2: false}))).Collect()
+	if !reflect.DeepEqual(keys, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", keys)
+	}
+	sorted := slices.Collect(iterator.SortOrdered(iterator.From([]int{3, 1, 2})).Seq())
+	if !reflect.DeepEqual(sorted, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", sorted)
+	}
+}
+
+func Test_Iterator_Seq2(t *testing.T) {
+	it := iterator.From([]string{"a", "bb", "c", "dd"}).Filter(func(val string) bool {
+		return len(val) == 2
+	})
+	result := map[int]string{}
+	for idx, val := range it.Seq2() {
+		result[idx] = val
+	}
+	if !reflect.DeepEqual(result, map[int]string{0: "bb", 1: "dd"}) {
+		t.Errorf("expected map[0:bb 1:dd], got %v", result)
+	}
+}
+
+func Test_FromSeq2(t *testing.T) {
+	result := iterator.FromSeq2(slices.All([]string{"a", "b", "c"})).Filter(func(e iterator.Entry[int, string]) bool {
+		return e.Key != 1
+	}).Collect()
+	expected := []iterator.Entry[int, string]{{Key: 0, Value: "a"}, {Key: 2, Value: "c"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	collected := maps.Collect(iterator.FromSeq2(maps.All(map[string]int{"x": 1})).Seq2())
+	if !reflect.DeepEqual(collected, map[int]iterator.Entry[string, int]{0: {Key: "x", Value: 1}}) {
+		t.Errorf("expected map[0:{x 1}], got %v", collected)
+	}
+}
+
+func Test_FromSeq_Stop(t *testing.T) {
+	cleanedUp := false
+	it := iterator.FromSeq(func(yield func(int) bool) {
+		defer func() { cleanedUp = true }()
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	})
+	for val := range it.Seq() {
+		if val == 3 {
+			break
+		}
+	}
+	if cleanedUp {
+		t.Fatal("expected the sequence to stay suspended until the iterator is stopped")
+	}
+	it.Stop()
+	if !cleanedUp {
+		t.Error("expected Stop to stop the sequence")
+	}
+	if val, ok := it.Next(); ok {
+		t.Errorf("expected no values after Stop, got %d", val)
+	}
+	it.Stop()
+}
@@ -0,0 +1,171 @@
+package iterator
+
+// Union returns a new iterator over the distinct values of the given iterators, in the order they are first found when
+// reading the iterators one after the other. Each iterator's chained operations are applied, and the iterators are only
+// read as values are requested, so an iterator isn't read until the ones before it have been exhausted.
+func Union[T comparable](its ...Of[T]) Of[T] {
+	return UnionBy(identity[T], its...)
+}
+
+// UnionBy is like Union, but values are considered equal if the given function returns the same key for them, and the first
+// value found with each key is kept.
+func UnionBy[T any, K comparable](key func(T) K, its ...Of[T]) Of[T] {
+	return FromSeq(func(yield func(T) bool) {
+		seen := make(map[K]struct{})
+		for _, it := range its {
+			for val := range it.Seq() {
+				k := key(val)
+				if _, ok := seen[k]; ok {
+					continue
+				}
+				seen[k] = struct{}{}
+				if !yield(val) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Intersect returns a new iterator over the distinct values of a that are also values of b, in the order they are found in
+// a. The chained operations of both iterators are applied. When the first value is requested, b is consumed entirely, so
+// its values can be looked up, while a is read as values are requested.
+func Intersect[T comparable](a, b Of[T]) Of[T] {
+	return IntersectBy(a, b, identity[T])
+}
+
+// IntersectBy is like Intersect, but values are considered equal if the given function returns the same key for them, and
+// the first value of a found with each key is kept.
+func IntersectBy[T any, K comparable](a, b Of[T], key func(T) K) Of[T] {
+	return filterByKeys(a, b, key, true)
+}
+
+// Difference returns a new iterator over the distinct values of a that aren't values of b, in the order they are found in
+// a. The iterators are read the same way as for Intersect.
+func Difference[T comparable](a, b Of[T]) Of[T] {
+	return DifferenceBy(a, b, identity[T])
+}
+
+// DifferenceBy is like Difference, but values are considered equal if the given function returns the same key for them, and
+// the first value of a found with each key is kept.
+func DifferenceBy[T any, K comparable](a, b Of[T], key func(T) K) Of[T] {
+	return filterByKeys(a, b, key, false)
+}
+
+// Pair is a combination of two values, such as those returned by Cross.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Cross returns a new iterator over every combination of a value of a with a value of b, which is the cartesian product of
+// the two iterators. The combinations are ordered by the values of a, then by the values of b, as in nested loops with a on
+// the outside. The chained operations of both iterators are applied. When the first combination is requested, b is consumed
+// entirely, so its values can be combined with each value of a, while a is read as combinations are requested, so it can be
+// infinite as long as the iterator is only consumed with methods that stop early.
+func Cross[A, B any](a Of[A], b Of[B]) Of[Pair[A, B]] {
+	return FromSeq(func(yield func(Pair[A, B]) bool) {
+		seconds := b.Collect()
+		if len(seconds) == 0 {
+			return
+		}
+		for first := range a.Seq() {
+			for _, second := range seconds {
+				if !yield(Pair[A, B]{First: first, Second: second}) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Diff applies the chained operations of both iterators and compares the values they produce, returning the values of
+// newer that aren't in older as added, in the order they are found in newer, and the values of older that aren't in newer
+// as removed, in the order they are found in older. Repeated values are matched one for one, so a value found twice in
+// older and once in newer is removed once. older is consumed first, then newer is read one value at a time.
+func Diff[T comparable](older, newer Of[T]) (added, removed []T) {
+	counts := make(map[T]int)
+	var olds []T
+	for val := range older.Seq() {
+		counts[val]++
+		olds = append(olds, val)
+	}
+	for val := range newer.Seq() {
+		if counts[val] > 0 {
+			counts[val]--
+			continue
+		}
+		added = append(added, val)
+	}
+	for _, val := range olds {
+		if counts[val] > 0 {
+			counts[val]--
+			removed = append(removed, val)
+		}
+	}
+	return added, removed
+}
+
+// DiffBy is like Diff, but matches the values of the two iterators by the key the given function returns for them, so
+// that a value whose key is in both can be reported as modified, as a pair of its older and newer values, if equal returns
+// false for them. Values matched by key whose equal returns true are left out of the result. Repeated keys are matched
+// one for one, in the order they are found.
+func DiffBy[T any, K comparable](older, newer Of[T], key func(T) K, equal func(a, b T) bool) (added, removed []T, modified []Pair[T, T]) {
+	unmatched := make(map[K][]int) // the indexes of the values of older with each key that haven't been matched yet
+	var olds []T
+	for val := range older.Seq() {
+		k := key(val)
+		unmatched[k] = append(unmatched[k], len(olds))
+		olds = append(olds, val)
+	}
+	matched := make([]bool, len(olds))
+	for val := range newer.Seq() {
+		k := key(val)
+		idxs := unmatched[k]
+		if len(idxs) == 0 {
+			added = append(added, val)
+			continue
+		}
+		idx := idxs[0]
+		unmatched[k] = idxs[1:]
+		matched[idx] = true
+		if !equal(olds[idx], val) {
+			modified = append(modified, Pair[T, T]{First: olds[idx], Second: val})
+		}
+	}
+	for idx, val := range olds {
+		if !matched[idx] {
+			removed = append(removed, val)
+		}
+	}
+	return added, removed, modified
+}
+
+// filterByKeys returns a new iterator over the values of a with distinct keys, keeping those whose key is a key of one of
+// the values of b if inB is true, or those whose key isn't if it's false.
+func filterByKeys[T any, K comparable](a, b Of[T], key func(T) K, inB bool) Of[T] {
+	return FromSeq(func(yield func(T) bool) {
+		keys := make(map[K]struct{})
+		for val := range b.Seq() {
+			keys[key(val)] = struct{}{}
+		}
+		seen := make(map[K]struct{})
+		for val := range a.Seq() {
+			k := key(val)
+			if _, ok := keys[k]; ok != inB {
+				continue
+			}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(val) {
+				return
+			}
+		}
+	})
+}
+
+func identity[T any](val T) T {
+	return val
+}
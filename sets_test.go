@@ -0,0 +1,170 @@
+package iterator_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_SetOperations(t *testing.T) {
+	tests := map[string]struct {
+		op       func(a, b iterator.Of[int]) iterator.Of[int]
+		a, b     []int
+		expected []int
+	}{
+		"union":                       {func(a, b iterator.Of[int]) iterator.Of[int] { return iterator.Union(a, b) }, []int{3, 1, 3, 2}, []int{2, 4, 1, 5}, []int{3, 1, 2, 4, 5}},
+		"union_empty":                 {func(a, b iterator.Of[int]) iterator.Of[int] { return iterator.Union(a, b) }, []int{}, []int{}, []int{}},
+		"intersect":                   {iterator.Intersect[int], []int{3, 1, 3, 2}, []int{2, 3, 5}, []int{3, 2}},
+		"intersect_disjoint":          {iterator.Intersect[int], []int{1, 2}, []int{3, 4}, []int{}},
+		"difference":                  {iterator.Difference[int], []int{3, 1, 3, 2, 1}, []int{2, 5}, []int{3, 1}},
+		"difference_empty_subtrahend": {iterator.Difference[int], []int{1, 1, 2}, []int{}, []int{1, 2}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := test.op(iterator.From(test.a), iterator.From(test.b)).Collect()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_SetOperations_By(t *testing.T) {
+	lower := func() iterator.Of[string] {
+		return iterator.FromValues("go", "rust", "zig")
+	}
+	upper := func() iterator.Of[string] {
+		return iterator.FromValues("Zig", "C", "GO")
+	}
+	if result := iterator.UnionBy(strings.ToLower, lower(), upper()).Collect(); !reflect.DeepEqual(result, []string{"go", "rust", "zig", "C"}) {
+		t.Errorf("expected [go rust zig C], got %v", result)
+	}
+	if result := iterator.IntersectBy(upper(), lower(), strings.ToLower).Collect(); !reflect.DeepEqual(result, []string{"Zig", "GO"}) {
+		t.Errorf("expected [Zig GO], got %v", result)
+	}
+	if result := iterator.DifferenceBy(lower(), upper(), strings.ToLower).Collect(); !reflect.DeepEqual(result, []string{"rust"}) {
+		t.Errorf("expected [rust], got %v", result)
+	}
+}
+
+func Test_Union_Lazy(t *testing.T) {
+	read := false
+	never := iterator.FromFunc(func() (int, bool) {
+		read = true
+		return 0, false
+	})
+	it := iterator.Union(iterator.FromValues(1, 2, 1).Map(func(val int) int {
+		return val * 10
+	}), never)
+	if val, ok := it.Next(); !ok || val != 10 {
+		t.Errorf("expected 10, got %d", val)
+	}
+	if val, ok := it.Next(); !ok || val != 20 {
+		t.Errorf("expected 20, got %d", val)
+	}
+	if read {
+		t.Error("expected the second iterator not to be read before the first is exhausted")
+	}
+	it.Stop()
+}
+
+func Test_Cross(t *testing.T) {
+	type combo = iterator.Pair[string, int]
+	tests := map[string]struct {
+		a        []string
+		b        []int
+		expected []combo
+	}{
+		"combinations": {[]string{"linux", "darwin"}, []int{1, 2}, []combo{{"linux", 1}, {"linux", 2}, {"darwin", 1}, {"darwin", 2}}},
+		"empty_a":      {[]string{}, []int{1, 2}, []combo{}},
+		"empty_b":      {[]string{"linux"}, []int{}, []combo{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.Cross(iterator.From(test.a), iterator.From(test.b)).Collect()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_Cross_Infinite(t *testing.T) {
+	it := iterator.Cross(iterator.FromSeq(func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}), iterator.FromValues("a", "b"))
+	defer it.Stop()
+	var result []iterator.Pair[int, string]
+	for pair := range it.Seq() {
+		if len(result) == 3 {
+			break
+		}
+		result = append(result, pair)
+	}
+	expected := []iterator.Pair[int, string]{{0, "a"}, {0, "b"}, {1, "a"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	tests := map[string]struct {
+		older, newer []int
+		added        []int
+		removed      []int
+	}{
+		"changes": {
+			older:   []int{1, 2, 3, 4},
+			newer:   []int{5, 2, 4, 6},
+			added:   []int{5, 6},
+			removed: []int{1, 3},
+		},
+		"repeated values": {
+			older:   []int{1, 1, 2},
+			newer:   []int{1, 2, 2},
+			added:   []int{2},
+			removed: []int{1},
+		},
+		"unchanged": {
+			older: []int{1, 2},
+			newer: []int{2, 1},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			added, removed := iterator.Diff(iterator.From(test.older), iterator.From(test.newer))
+			if !reflect.DeepEqual(added, test.added) {
+				t.Errorf("expected %v to be added, got %v", test.added, added)
+			}
+			if !reflect.DeepEqual(removed, test.removed) {
+				t.Errorf("expected %v to be removed, got %v", test.removed, removed)
+			}
+		})
+	}
+}
+
+func Test_DiffBy(t *testing.T) {
+	type record struct {
+		id   int
+		name string
+	}
+	older := []record{{1, "ana"}, {2, "beto"}, {3, "caro"}}
+	newer := []record{{3, "carolina"}, {1, "ana"}, {4, "dani"}}
+	added, removed, modified := iterator.DiffBy(iterator.From(older), iterator.From(newer), func(r record) int {
+		return r.id
+	}, func(a, b record) bool {
+		return a == b
+	})
+	if expected := []record{{4, "dani"}}; !reflect.DeepEqual(added, expected) {
+		t.Errorf("expected %+v to be added, got %+v", expected, added)
+	}
+	if expected := []record{{2, "beto"}}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected %+v to be removed, got %+v", expected, removed)
+	}
+	if expected := []iterator.Pair[record, record]{{First: record{3, "caro"}, Second: record{3, "carolina"}}}; !reflect.DeepEqual(modified, expected) {
+		t.Errorf("expected %+v to be modified, got %+v", expected, modified)
+	}
+}
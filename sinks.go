@@ -0,0 +1,32 @@
+package iterator
+
+import "io"
+
+// WriteTo applies all of the chained operations to the iterator and writes each value left to w, as formatted by format,
+// one at a time, so the values are never all held in memory unless the pipeline contains an operation that needs every
+// value at once, such as Sort. Nothing is written between values, so format should add any delimiter needed, such as a
+// trailing newline. WriteTo returns the number of bytes written, and stops at the first error, whether it comes from a
+// TryMap or TryFilter function, the source, or writing.
+func WriteTo[T any](it Of[T], w io.Writer, format func(T) []byte) (int64, error) {
+	var written int64
+	err := tryEach(it, func(val T) error {
+		n, err := w.Write(format(val))
+		written += int64(n)
+		return err
+	})
+	return written, err
+}
+
+// tryEach applies all of the chained operations to the iterator, calling fn with each value left, and stops at the first
+// error returned by a Try operation, the source, or fn, returning it.
+func tryEach[T any](it Of[T], fn func(T) error) error {
+	if impl, ok := it.(*iter[T]); ok {
+		return impl.tryProcess(fn)
+	}
+	for val := range it.Seq() { // a custom implementation of Of can only report values, not the errors of its operations
+		if err := fn(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package iterator_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_WriteTo(t *testing.T) {
+	line := func(val int) []byte {
+		return []byte(strconv.Itoa(val) + "\n")
+	}
+	var buf bytes.Buffer
+	n, err := iterator.WriteTo(iterator.FromValues(1, 22, 3, 44).Filter(func(val int) bool {
+		return val > 10
+	}), &buf, line)
+	if err != nil || n != 6 || buf.String() != "22\n44\n" {
+		t.Errorf("expected 6 bytes of %q, got %d bytes of %q and %v", "22\n44\n", n, buf.String(), err)
+	}
+
+	if n, err := iterator.WriteTo(iterator.FromValues(1, 2), failingWriter{}, line); err == nil || n != 0 {
+		t.Errorf("expected the write error, got %d bytes and %v", n, err)
+	}
+
+	errOdd := errors.New("odd")
+	buf.Reset()
+	n, err = iterator.WriteTo(iterator.FromValues(2, 3, 4).TryMap(func(val int) (int, error) {
+		if val%2 == 1 {
+			return val, errOdd
+		}
+		return val, nil
+	}), &buf, line)
+	if !errors.Is(err, errOdd) || n != 2 || buf.String() != "2\n" {
+		t.Errorf("expected error %v after writing %q, got %v after writing %d bytes of %q", errOdd, "2\n", err, n, buf.String())
+	}
+}
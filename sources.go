@@ -0,0 +1,441 @@
+package iterator
+
+import (
+	"bufio"
+	"fmt"
+	goiter "iter"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// Range returns a new iterator over the integers from start up to, but not including, end, increasing by step each time.
+// If step is negative, the iterator counts down from start to end instead. The values are generated lazily, so no slice is
+// allocated for them. Range panics if step is zero. The options are the same as for From, although CopySource has no effect.
+func Range(start, end, step int, opts ...FromOption) Of[int] {
+	return RangeOf(start, end, step, opts...)
+}
+
+// RangeOf is the generic version of Range, returning an iterator over values of any integer type. Iterators over unsigned
+// types can only count up, as their step can't be negative.
+func RangeOf[T Integer](start, end, step T, opts ...FromOption) Of[T] {
+	if step == 0 {
+		panic("iterator: Range step must not be zero")
+	}
+	// the arithmetic is done on uint64s, which wrap around correctly even when the distance between start and end doesn't
+	// fit in T, as is the case for Range(-100, 100, 1) on int8s
+	var distance, stride uint64
+	if step > 0 && start < end {
+		distance, stride = uint64(end)-uint64(start), uint64(step)
+	} else if step < 0 && start > end {
+		distance, stride = uint64(start)-uint64(end), -uint64(step)
+	}
+	size := 0
+	if distance > 0 {
+		size = int((distance-1)/stride + 1)
+	}
+	it := newIter(nil, func(it *iter[T]) (T, bool) {
+		if it.nextIndex >= size {
+			return *new(T), false
+		}
+		defer func() { it.nextIndex++ }()
+		return start + T(it.nextIndex)*step, true
+	}, size, opts)
+	it.sized = true
+	return it
+}
+
+// FromFunc returns a new iterator that pulls its elements from the given function until it reports that there are no more
+// by returning false. The function isn't called again once it has returned false. This makes it possible to iterate over
+// lazily generated or infinite sequences without an in-memory slice; an infinite source should only be consumed with
+// methods that stop early, as Collect would never return. As a function can't be rewound, Reset has no effect on the
+// elements returned. The options are the same as for From, although CopySource has no effect.
+func FromFunc[T any](fn func() (T, bool), opts ...FromOption) Of[T] {
+	return fromFunc(fn, opts)
+}
+
+func fromFunc[T any](fn func() (T, bool), opts []FromOption) *iter[T] {
+	done := false
+	return newIter(nil, func(it *iter[T]) (T, bool) {
+		if done {
+			return *new(T), false
+		}
+		val, ok := fn()
+		if !ok {
+			done = true
+			return *new(T), false
+		}
+		it.nextIndex++
+		return val, true
+	}, 0, opts)
+}
+
+// Source is a source of elements that can be wrapped by an iterator using FromSource, so that custom sources get all of the
+// chained operations without reimplementing them. Next returns the next element and whether there was one; it isn't called
+// again once it has returned false, until the source is rewound. Len returns the number of elements the source holds, if
+// it's known, which is used to size the slices allocated when collecting. Reset rewinds the source to its first element,
+// returning false if it can't be rewound. A source that can fail can also have an Err method, as sql.Rows and
+// bufio.Scanner do, which is called once Next returns false; the error it returns, if any, is returned by TryCollect.
+type Source[T any] interface {
+	Next() (T, bool)
+	Len() (int, bool)
+	Reset() bool
+}
+
+// FromSource returns a new iterator that pulls its elements from the given source. Calling Reset on the iterator also
+// rewinds the source, which is done before the next element is read, so an iterator over a source that can be rewound can
+// be collected more than once. If the source can't be rewound, Reset has no effect on the elements returned, as with
+// FromFunc. Rewinding the source also clears the error returned by its Err method, if it has one. The options are the
+// same as for From, although CopySource has no effect.
+func FromSource[T any](src Source[T], opts ...FromOption) Of[T] {
+	size, sized := src.Len()
+	if !sized || size < 0 {
+		size, sized = 0, false
+	}
+	var rewind atomic.Bool // set by Reset, and checked by whichever goroutine reads next, such as the one started by Prefetch
+	done := false
+	it := newIter(nil, func(it *iter[T]) (T, bool) {
+		if rewind.Swap(false) && src.Reset() {
+			done, it.err = false, nil
+		}
+		if done {
+			return *new(T), false
+		}
+		val, ok := src.Next()
+		if !ok {
+			done = true
+			if failing, ok := src.(interface{ Err() error }); ok {
+				if err := failing.Err(); err != nil {
+					it.err = err
+				}
+			}
+			return *new(T), false
+		}
+		it.nextIndex++
+		return val, true
+	}, size, opts)
+	it.sized = sized
+	next := it.resetFunc // set if the OnProgress option is used
+	it.resetFunc = func() {
+		rewind.Store(true)
+		if next != nil {
+			next()
+		}
+	}
+	return it
+}
+
+// FromChannel returns a new iterator that receives its elements from the given channel until it is closed, so values
+// produced by an existing concurrent pipeline can flow into the chained operations. Calls to Next block while the channel
+// is empty, and the iterator is only exhausted once the channel has been closed; collecting from a channel that is never
+// closed never returns. As received values can't be received again, Reset has no effect on the elements returned. The
+// options are the same as for From, although CopySource has no effect.
+func FromChannel[T any](ch <-chan T, opts ...FromOption) Of[T] {
+	return FromFunc(func() (T, bool) {
+		val, ok := <-ch
+		return val, ok
+	}, opts...)
+}
+
+// FanIn returns a new iterator that receives its elements from all of the given channels, in whatever order they arrive,
+// until every channel is closed, merging several producers into a single pipeline. The channels are only read once the
+// first value is requested, each on its own goroutine. Calling Stop releases those goroutines, leaving the rest of the
+// values in the channels, so call it when abandoning the iterator before the channels are closed. As with FromChannel,
+// Reset has no effect on the elements returned.
+func FanIn[T any](chs ...<-chan T) Of[T] {
+	merged := make(chan T)
+	done := make(chan struct{})
+	started := false
+	it := fromFunc(func() (T, bool) {
+		if !started {
+			started = true
+			wg := sync.WaitGroup{}
+			for _, ch := range chs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for val := range ch {
+						select {
+						case merged <- val:
+						case <-done:
+							return
+						}
+					}
+				}()
+			}
+			go func() {
+				wg.Wait()
+				close(merged)
+			}()
+		}
+		val, ok := <-merged
+		return val, ok
+	}, nil)
+	it.onStop(func() {
+		close(done)
+	})
+	return it
+}
+
+// FromGlob returns a new iterator over the elements of every file matching the given pattern, concatenated in the lexical
+// order filepath.Glob returns them in. Each file is only opened once the elements of the previous one have been consumed,
+// and contributes the values left after applying the operations chained to the iterator returned by open. The pattern is
+// matched when the iterator is created, so the only possible error is filepath.ErrBadPattern. If open fails, the iterator
+// ends there, and the error is returned by TryCollect and TryForEach; other methods only see the elements before it. As
+// with FromFunc, Reset has no effect on the elements returned. The options are the same as for From, although CopySource
+// has no effect.
+func FromGlob[T any](pattern string, open func(path string) (Of[T], error), opts ...FromOption) (Of[T], error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var next func() (T, bool)
+	var stop func()
+	it := newIter(nil, func(it *iter[T]) (T, bool) {
+		for {
+			if next == nil { // the previous file is exhausted, move on to the next one
+				if len(paths) == 0 || it.err != nil {
+					return *new(T), false
+				}
+				file, err := open(paths[0])
+				paths = paths[1:]
+				if err != nil {
+					it.err = err
+					return *new(T), false
+				}
+				next, stop = goiter.Pull(file.Seq())
+			}
+			if val, ok := next(); ok {
+				it.nextIndex++
+				return val, true
+			}
+			stop()
+			next = nil
+		}
+	}, 0, opts)
+	it.onStop(func() {
+		if next != nil {
+			stop()
+		}
+	})
+	return it, nil
+}
+
+// Paginate returns a new iterator over the items of every page of a paginated source, such as an API listing resources,
+// in order. fetch is called with the token of the page to fetch, which is "" for the first page, and returns its items
+// along with the token of the next page, or "" if it was the last. Each page is only fetched once the items of the
+// previous one have been consumed, so only one page is held in memory at a time, and pages after the ones needed are
+// never fetched. Empty pages are skipped. If fetch fails, the iterator ends there, and the error is returned by TryCollect
+// and TryForEach; other methods only see the items before it. As with FromFunc, Reset has no effect on the elements
+// returned. The options are the same as for From, although CopySource has no effect.
+func Paginate[T any](fetch func(pageToken string) (items []T, next string, err error), opts ...FromOption) Of[T] {
+	var page []T
+	token, last := "", false
+	return newIter(nil, func(it *iter[T]) (T, bool) {
+		for len(page) == 0 {
+			if last || it.err != nil {
+				return *new(T), false
+			}
+			items, next, err := fetch(token)
+			if err != nil {
+				it.err = fmt.Errorf("iterator: fetching page %q: %w", token, err)
+				return *new(T), false
+			}
+			page, token, last = items, next, next == ""
+		}
+		val := page[0]
+		page = page[1:]
+		it.nextIndex++
+		return val, true
+	}, 0, opts)
+}
+
+// Entry is a key/value pair from a map.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// FromMap returns a new iterator over the entries of the given map. The entries are copied out of the map when the iterator
+// is created, so later changes to the map aren't reflected in the iterator. As with ranging over a map, the order of the
+// entries is unspecified. The options are the same as for From, although CopySource has no effect.
+func FromMap[K comparable, V any](m map[K]V, opts ...FromOption) Of[Entry[K, V]] {
+	entries := make([]Entry[K, V], 0, len(m))
+	for k, v := range m {
+		entries = append(entries, Entry[K, V]{Key: k, Value: v})
+	}
+	return From(entries, opts...)
+}
+
+// Keys returns a new iterator over the keys of the given map, in unspecified order. The keys are copied out of the map when
+// the iterator is created. The options are the same as for From, although CopySource has no effect.
+func Keys[K comparable, V any](m map[K]V, opts ...FromOption) Of[K] {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return From(keys, opts...)
+}
+
+// Values returns a new iterator over the values of the given map, in unspecified order. The values are copied out of the map
+// when the iterator is created. The options are the same as for From, although CopySource has no effect.
+func Values[K comparable, V any](m map[K]V, opts ...FromOption) Of[V] {
+	values := make([]V, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return From(values, opts...)
+}
+
+// FromScanner returns a new iterator over the tokens of the given scanner, such as the lines of a file with the default
+// bufio.ScanLines split function. Tokens are scanned one at a time as they are requested, and each is copied into a new
+// string, so it can be kept after the next one is scanned. If scanning fails, the iteration ends there, and the error is
+// returned by TryCollect and TryForEach; other methods only see the tokens before it. As with FromFunc, Reset has no effect
+// on the elements returned. The options are the same as for From, although CopySource has no effect.
+func FromScanner(scanner *bufio.Scanner, opts ...FromOption) Of[string] {
+	done := false
+	return newIter(nil, func(it *iter[string]) (string, bool) {
+		if done {
+			return "", false
+		}
+		if !scanner.Scan() {
+			done = true
+			if err := scanner.Err(); err != nil {
+				it.err = fmt.Errorf("iterator: scanning token %d: %w", it.nextIndex, err)
+			}
+			return "", false
+		}
+		it.nextIndex++
+		return scanner.Text(), true
+	}, 0, opts)
+}
+
+// Runes returns a new iterator over the runes of the given string, decoded as UTF-8 in the same way as ranging over it.
+// The string is decoded when the iterator is created, so the iterator can be reset like one over a slice.
+func Runes(s string, opts ...FromOption) Of[rune] {
+	return From([]rune(s), opts...)
+}
+
+// Bytes returns a new iterator over the bytes of the given string. The bytes are copied when the iterator is created, so
+// the iterator can be reset like one over a slice.
+func Bytes(s string, opts ...FromOption) Of[byte] {
+	return From([]byte(s), opts...)
+}
+
+// FromValues returns a new iterator over the given values, which saves building a slice literal for small pipelines and
+// tests. The iterator reads from the variadic argument slice directly, so passing an existing slice with the ... syntax
+// behaves the same as From without options.
+func FromValues[T any](vals ...T) Of[T] {
+	return From(vals)
+}
+
+// Empty returns a new iterator with no elements, which is useful as a default return value for functions returning an
+// iterator.
+func Empty[T any]() Of[T] {
+	return From([]T{})
+}
+
+// Once returns a new iterator over the single given value, for functions returning an iterator that only have one value to
+// return. Like any other iterator, it can be reset to return the value again.
+func Once[T any](val T) Of[T] {
+	return From([]T{val})
+}
+
+// Err returns a new iterator with no elements that reports the given error from TryCollect and TryForEach, so functions
+// returning an iterator can propagate a failure to set it up without also returning an error. Other methods see an empty
+// iterator.
+func Err[T any](err error) Of[T] {
+	it := newIter(nil, func(*iter[T]) (T, bool) {
+		return *new(T), false
+	}, 0, nil)
+	it.err = err
+	return it
+}
+
+// If returns then if cond is true, and otherwise if it isn't, so that choosing between sources can be written inline.
+func If[T any](cond bool, then, otherwise Of[T]) Of[T] {
+	if cond {
+		return then
+	}
+	return otherwise
+}
+
+// FirstNonEmpty returns a new iterator over the values of the first of the given iterators that has any left after
+// applying its chained operations, such as falling back from a cache to a database to a default. The iterators are tried
+// in order, lazily, when the first value is requested, and the ones after the first non-empty iterator are never read. As
+// with FromFunc, Reset has no effect on the elements returned.
+func FirstNonEmpty[T any](its ...Of[T]) Of[T] {
+	var next func() (T, bool)
+	var stop func()
+	it := fromFunc(func() (T, bool) {
+		for next == nil { // look for the first iterator with a value, which is kept for the rest of the iteration
+			if len(its) == 0 {
+				return *new(T), false
+			}
+			next, stop = goiter.Pull(its[0].Seq())
+			its = its[1:]
+			if val, ok := next(); ok {
+				return val, true
+			}
+			stop()
+			next = nil
+		}
+		val, ok := next()
+		if !ok {
+			stop()
+		}
+		return val, ok
+	}, nil)
+	it.onStop(func() {
+		if next != nil {
+			stop()
+		}
+	})
+	return it
+}
+
+// Intersperse returns a new iterator over the values left after applying the chained operations of the given iterator, with
+// sep between each pair of consecutive values, such as commas between fields before writing them out. Values are streamed
+// as they are consumed, so the iterator is only read as values are requested. As with FromFunc, Reset has no effect on the
+// elements returned.
+func Intersperse[T any](it Of[T], sep T) Of[T] {
+	return FromSeq(func(yield func(T) bool) {
+		first := true
+		for val := range it.Seq() {
+			if !first && !yield(sep) {
+				return
+			}
+			first = false
+			if !yield(val) {
+				return
+			}
+		}
+	})
+}
+
+// ChunkReduce returns a new iterator over one aggregate for each chunk of size consecutive values left after applying the
+// chained operations of the given iterator. Each chunk is reduced as Reduce does, starting from initial, and the last
+// chunk holds whatever is left, so its aggregate may cover fewer values. Values are reduced as they are read, so chunks
+// are never held in memory, and the iterator is only read as aggregates are requested. As with FromFunc, Reset has no
+// effect on the elements returned. ChunkReduce panics if size is less than 1.
+func ChunkReduce[T, A any](it Of[T], size int, fn func(A, T) A, initial A) Of[A] {
+	if size < 1 {
+		panic("iterator: ChunkReduce size must be positive")
+	}
+	return FromSeq(func(yield func(A) bool) {
+		acc, n := initial, 0
+		for val := range it.Seq() {
+			acc, n = fn(acc, val), n+1
+			if n < size {
+				continue
+			}
+			if !yield(acc) {
+				return
+			}
+			acc, n = initial, 0
+		}
+		if n > 0 {
+			yield(acc)
+		}
+	})
+}
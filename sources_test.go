@@ -0,0 +1,674 @@
+package iterator_test
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Range(t *testing.T) {
+	tests := map[string]struct {
+		start, end, step int
+		expected         []int
+	}{
+		"ascending":       {0, 5, 1, []int{0, 1, 2, 3, 4}},
+		"step":            {0, 10, 3, []int{0, 3, 6, 9}},
+		"descending":      {5, 0, -2, []int{5, 3, 1}},
+		"empty":           {5, 5, 1, []int{}},
+		"wrong direction": {0, 5, -1, []int{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result := iterator.Range(test.start, test.end, test.step).Collect()
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_RangeOf(t *testing.T) {
+	it := iterator.RangeOf[int8](-100, 100, 50)
+	if result := it.Collect(); !reflect.DeepEqual(result, []int8{-100, -50, 0, 50}) {
+		t.Errorf("expected [-100 -50 0 50], got %v", result)
+	}
+	it.Reset()
+	if result := it.Map(func(val int8) int8 {
+		return val / 10
+	}).Collect(); !reflect.DeepEqual(result, []int8{-10, -5, 0, 5}) {
+		t.Errorf("expected [-10 -5 0 5], got %v", result)
+	}
+	if result := iterator.RangeOf[uint8](100, 255, 50).Collect(); !reflect.DeepEqual(result, []uint8{100, 150, 200, 250}) {
+		t.Errorf("expected [100 150 200 250], got %v", result)
+	}
+}
+
+func Test_Range_ZeroStep(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Range to panic")
+		}
+	}()
+	iterator.Range(0, 10, 0)
+}
+
+func Test_FromFunc(t *testing.T) {
+	calls := 0
+	a, b := 0, 1
+	fibonacci := func() (int, bool) {
+		calls++
+		if a > 50 {
+			return 0, false
+		}
+		val := a
+		a, b = b, a+b
+		return val, true
+	}
+	it := iterator.FromFunc(fibonacci).Filter(func(val int) bool {
+		return val%2 == 0
+	})
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{0, 2, 8, 34}) {
+		t.Errorf("expected [0 2 8 34], got %v", result)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("expected the iterator to be exhausted")
+	}
+	if calls != 11 {
+		t.Errorf("expected the function to stop being called once exhausted, got %d calls", calls)
+	}
+}
+
+type countdown struct {
+	from, next int
+	rewind     bool
+}
+
+func (c *countdown) Next() (int, bool) {
+	if c.next <= 0 {
+		return 0, false
+	}
+	c.next--
+	return c.next + 1, true
+}
+
+func (c *countdown) Len() (int, bool) {
+	return c.next, c.rewind
+}
+
+func (c *countdown) Reset() bool {
+	if c.rewind {
+		c.next = c.from
+	}
+	return c.rewind
+}
+
+func Test_FromSource(t *testing.T) {
+	tests := map[string]struct {
+		rewind   bool
+		expected []int
+	}{
+		"rewindable": {
+			rewind:   true,
+			expected: []int{3, 2, 1},
+		},
+		"not rewindable": {
+			rewind:   false,
+			expected: []int{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.FromSource[int](&countdown{from: 3, next: 3, rewind: test.rewind}).Map(func(val int) int {
+				return val * 10
+			})
+			if result := it.Collect(); !reflect.DeepEqual(result, []int{30, 20, 10}) {
+				t.Errorf("expected [30 20 10], got %v", result)
+			}
+			it.Reset(iterator.ClearOperations(true))
+			if result := it.Collect(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+// failingCountdown is a countdown that fails the first time it runs out, as a source reading from a network might.
+type failingCountdown struct {
+	countdown
+	err error
+}
+
+func (c *failingCountdown) Err() error {
+	return c.err
+}
+
+func (c *failingCountdown) Reset() bool {
+	c.err = nil
+	return c.countdown.Reset()
+}
+
+func Test_FromSource_Err(t *testing.T) {
+	failure := errors.New("connection reset")
+	it := iterator.FromSource[int](&failingCountdown{countdown: countdown{from: 3, next: 3, rewind: true}, err: failure})
+	if result, err := it.TryCollect(); !errors.Is(err, failure) || result != nil {
+		t.Errorf("expected nil and %v, got %v and %v", failure, result, err)
+	}
+	it.Reset()
+	if result, err := it.TryCollect(); err != nil || !reflect.DeepEqual(result, []int{3, 2, 1}) {
+		t.Errorf("expected [3 2 1] and no error, got %v and %v", result, err)
+	}
+}
+
+func Test_FromChannel(t *testing.T) {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 5; i++ {
+			ch <- i
+		}
+	}()
+	result := iterator.FromChannel(ch).Map(func(val int) int {
+		return val * 10
+	}).Collect()
+	if !reflect.DeepEqual(result, []int{10, 20, 30, 40, 50}) {
+		t.Errorf("expected [10 20 30 40 50], got %v", result)
+	}
+}
+
+func Test_FromChannel_RoundTrip(t *testing.T) {
+	source := iterator.From([]string{"a", "b", "a", "c"})
+	result := iterator.FromChannel(source.Channel()).Unique().Collect()
+	if !reflect.DeepEqual(result, []string{"a", "b", "c"}) {
+		t.Errorf("expected [a b c], got %v", result)
+	}
+}
+
+func Test_FanIn(t *testing.T) {
+	produce := func(vals ...int) <-chan int {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for _, val := range vals {
+				ch <- val
+			}
+		}()
+		return ch
+	}
+	result := iterator.SortOrdered(iterator.FanIn(produce(1, 3, 5), produce(2, 4), produce()).Filter(func(val int) bool {
+		return val > 1
+	})).Collect()
+	if !reflect.DeepEqual(result, []int{2, 3, 4, 5}) {
+		t.Errorf("expected [2 3 4 5], got %v", result)
+	}
+	if result := iterator.FanIn[int]().Collect(); !reflect.DeepEqual(result, []int{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+
+	endless, quit := make(chan int), make(chan struct{})
+	defer close(quit)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case endless <- i:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	it := iterator.FanIn(endless)
+	if val, ok := it.Next(); !ok || val != 0 {
+		t.Errorf("expected 0, got %d", val)
+	}
+	it.Stop()
+	if val, ok := it.Next(); ok {
+		t.Errorf("expected no values after Stop, got %d", val)
+	}
+}
+
+func Test_FromMap(t *testing.T) {
+	ages := map[string]int{"Felicita": 23, "Luis": 24, "Juan": 25}
+	result := iterator.FromMap(ages).Filter(func(e iterator.Entry[string, int]) bool {
+		return e.Value > 23
+	}).Sort(func(a, b iterator.Entry[string, int]) bool {
+		return a.Key < b.Key
+	}).Collect()
+	expected := []iterator.Entry[string, int]{{Key: "Juan", Value: 25}, {Key: "Luis", Value: 24}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	if keys := iterator.SortOrdered(iterator.Keys(ages)).Collect(); !reflect.DeepEqual(keys, []string{"Felicita", "Juan", "Luis"}) {
+		t.Errorf("expected [Felicita Juan Luis], got %v", keys)
+	}
+	if values := iterator.SortOrdered(iterator.Values(ages)).Collect(); !reflect.DeepEqual(values, []int{23, 24, 25}) {
+		t.Errorf("expected [23 24 25], got %v", values)
+	}
+}
+
+func Test_FromScanner(t *testing.T) {
+	it := iterator.FromScanner(bufio.NewScanner(strings.NewReader("alpha\n\nbeta\r\ngamma"))).Filter(func(line string) bool {
+		return line != ""
+	})
+	if result, err := it.TryCollect(); err != nil || !reflect.DeepEqual(result, []string{"alpha", "beta", "gamma"}) {
+		t.Errorf("expected [alpha beta gamma], got %v and %v", result, err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader("short\n" + strings.Repeat("x", 100)))
+	scanner.Buffer(make([]byte, 10), 10)
+	result, err := iterator.FromScanner(scanner).TryCollect()
+	if !errors.Is(err, bufio.ErrTooLong) || result != nil {
+		t.Errorf("expected error %v, got %v and %v", bufio.ErrTooLong, result, err)
+	}
+
+	words := bufio.NewScanner(strings.NewReader("the quick  brown fox"))
+	words.Split(bufio.ScanWords)
+	if result := iterator.FromScanner(words).Collect(); !reflect.DeepEqual(result, []string{"the", "quick", "brown", "fox"}) {
+		t.Errorf("expected [the quick brown fox], got %v", result)
+	}
+}
+
+func Test_Runes_Bytes(t *testing.T) {
+	if result := iterator.Runes("añb").Collect(); !reflect.DeepEqual(result, []rune{'a', 'ñ', 'b'}) {
+		t.Errorf("expected [a ñ b], got %q", result)
+	}
+	if result := iterator.Bytes("añ").Collect(); !reflect.DeepEqual(result, []byte{'a', 0xc3, 0xb1}) {
+		t.Errorf("expected [a 0xc3 0xb1], got %v", result)
+	}
+	if result := iterator.Runes("").Collect(); !reflect.DeepEqual(result, []rune{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+}
+
+func Test_FromValues(t *testing.T) {
+	if result := iterator.FromValues(3, 1, 2).Collect(); !reflect.DeepEqual(result, []int{3, 1, 2}) {
+		t.Errorf("expected [3 1 2], got %v", result)
+	}
+	if result := iterator.FromValues[string]().Collect(); !reflect.DeepEqual(result, []string{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+}
+
+func Test_Empty(t *testing.T) {
+	it := iterator.Empty[int]()
+	if _, ok := it.Next(); ok {
+		t.Error("expected no elements")
+	}
+	if result := it.Map(func(val int) int {
+		return val + 1
+	}).Collect(); !reflect.DeepEqual(result, []int{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+}
+
+func Test_Empty_Once_Terminals(t *testing.T) {
+	tests := map[string]struct {
+		newIt    func() iterator.Of[int]
+		expected []int
+	}{
+		"empty": {iterator.Empty[int], []int{}},
+		"once":  {func() iterator.Of[int] { return iterator.Once(7) }, []int{7}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sorted := iterator.SortOrdered(test.newIt()).Shuffle().Unique().Collect()
+			if !reflect.DeepEqual(sorted, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, sorted)
+			}
+			if result, err := test.newIt().TryMap(func(val int) (int, error) {
+				return val, nil
+			}).TryCollect(); err != nil || !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v and %v", test.expected, result, err)
+			}
+			if sample := test.newIt().Sample(3); len(sample) != len(test.expected) {
+				t.Errorf("expected a sample of %d, got %v", len(test.expected), sample)
+			}
+			var seq []int
+			for val := range test.newIt().Seq() {
+				seq = append(seq, val)
+			}
+			if len(seq) != len(test.expected) {
+				t.Errorf("expected %v from Seq, got %v", test.expected, seq)
+			}
+			it := test.newIt()
+			it.Collect()
+			it.Reset()
+			if result := it.Collect(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v after Reset, got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_FromGlob(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"b.txt": "3\n4", "a.txt": "1\n2", "c.log": "5"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var opened []string
+	open := func(path string) (iterator.Of[string], error) {
+		opened = append(opened, filepath.Base(path))
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return iterator.From(strings.Split(string(content), "\n")), nil
+	}
+	it, err := iterator.FromGlob(filepath.Join(dir, "*.txt"), open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := it.Next(); val != "1" || len(opened) != 1 {
+		t.Errorf("expected only the first file to be opened, got %v after reading %q", opened, val)
+	}
+	if result := it.Collect(); !reflect.DeepEqual(result, []string{"2", "3", "4"}) {
+		t.Errorf("expected [2 3 4], got %v", result)
+	}
+
+	if _, err := iterator.FromGlob("[", open); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Errorf("expected %v, got %v", filepath.ErrBadPattern, err)
+	}
+
+	errOpen := errors.New("open failed")
+	it, _ = iterator.FromGlob(filepath.Join(dir, "*.txt"), func(path string) (iterator.Of[string], error) {
+		if filepath.Base(path) == "b.txt" {
+			return nil, errOpen
+		}
+		return open(path)
+	})
+	if result, err := it.TryCollect(); !errors.Is(err, errOpen) || result != nil {
+		t.Errorf("expected error %v, got %v and %v", errOpen, result, err)
+	}
+}
+
+func Test_Err(t *testing.T) {
+	errSetup := errors.New("connection refused")
+	if result, err := iterator.Err[int](errSetup).Map(func(val int) int {
+		return val * 2
+	}).TryCollect(); !errors.Is(err, errSetup) || result != nil {
+		t.Errorf("expected error %v, got %v and %v", errSetup, result, err)
+	}
+	if err := iterator.Err[int](errSetup).TryForEach(func(int) error {
+		t.Error("expected no elements")
+		return nil
+	}); !errors.Is(err, errSetup) {
+		t.Errorf("expected error %v, got %v", errSetup, err)
+	}
+	if result := iterator.Err[int](errSetup).Collect(); !reflect.DeepEqual(result, []int{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+}
+
+func Test_If(t *testing.T) {
+	cached, fresh := iterator.FromValues(1), iterator.FromValues(2)
+	if result := iterator.If(true, cached, fresh).Collect(); !reflect.DeepEqual(result, []int{1}) {
+		t.Errorf("expected [1], got %v", result)
+	}
+	if result := iterator.If(false, cached, fresh).Collect(); !reflect.DeepEqual(result, []int{2}) {
+		t.Errorf("expected [2], got %v", result)
+	}
+}
+
+func Test_FirstNonEmpty(t *testing.T) {
+	read := false
+	never := iterator.FromFunc(func() (int, bool) {
+		read = true
+		return 0, false
+	})
+	cache := iterator.FromValues(1, 3).Filter(func(val int) bool {
+		return val%2 == 0
+	})
+	it := iterator.FirstNonEmpty(iterator.Empty[int](), cache, iterator.FromValues(4, 6), never)
+	if result := it.Collect(); !reflect.DeepEqual(result, []int{4, 6}) {
+		t.Errorf("expected [4 6], got %v", result)
+	}
+	if read {
+		t.Error("expected the iterators after the first non-empty one not to be read")
+	}
+	if result := iterator.FirstNonEmpty[int]().Collect(); !reflect.DeepEqual(result, []int{}) {
+		t.Errorf("expected [], got %v", result)
+	}
+}
+
+func Test_Intersperse(t *testing.T) {
+	tests := map[string]struct {
+		source   []string
+		expected []string
+	}{
+		"several": {[]string{"a", "", "b", "c"}, []string{"a", ",", "b", ",", "c"}},
+		"single":  {[]string{"a"}, []string{"a"}},
+		"empty":   {[]string{}, []string{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			it := iterator.Intersperse(iterator.From(test.source).Filter(func(val string) bool {
+				return val != ""
+			}), ",")
+			if result := it.Collect(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, result)
+			}
+		})
+	}
+}
+
+func Test_ChunkReduce(t *testing.T) {
+	sum := func(acc, val int) int {
+		return acc + val
+	}
+	tests := map[string]struct {
+		source   []int
+		size     int
+		expected []int
+	}{
+		"uneven": {
+			source:   []int{1, 2, 3, 4, 5, 6, 7},
+			size:     3,
+			expected: []int{6, 15, 7},
+		},
+		"exact": {
+			source:   []int{1, 2, 3, 4},
+			size:     2,
+			expected: []int{3, 7},
+		},
+		"empty": {
+			source:   []int{},
+			size:     2,
+			expected: []int{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := iterator.ChunkReduce(iterator.From(test.source), test.size, sum, 0).Collect(); !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+
+	lengths := iterator.ChunkReduce(iterator.From([]string{"a", "bb", "ccc"}), 2, func(acc int, val string) int {
+		return acc + len(val)
+	}, 0).Collect()
+	if !reflect.DeepEqual(lengths, []int{3, 3}) {
+		t.Errorf("expected [3 3], got %v", lengths)
+	}
+}
+
+func Test_Paginate(t *testing.T) {
+	pages := map[string]struct {
+		items []int
+		next  string
+	}{
+		"":   {items: []int{1, 2}, next: "p2"},
+		"p2": {items: []int{}, next: "p3"},
+		"p3": {items: []int{3}, next: "p4"},
+		"p4": {items: []int{4, 5}},
+	}
+	var fetched []string
+	fetch := func(token string) ([]int, string, error) {
+		fetched = append(fetched, token)
+		page, ok := pages[token]
+		if !ok {
+			return nil, "", errors.New("unknown page")
+		}
+		return page.items, page.next, nil
+	}
+	if result := iterator.Paginate(fetch).CollectN(3); !reflect.DeepEqual(result, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", result)
+	}
+	if !reflect.DeepEqual(fetched, []string{"", "p2", "p3"}) {
+		t.Errorf("expected only the pages needed to be fetched, got %q", fetched)
+	}
+	if result, err := iterator.Paginate(fetch).TryCollect(); err != nil || !reflect.DeepEqual(result, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected [1 2 3 4 5], got %v, %v", result, err)
+	}
+
+	pages["p3"] = struct {
+		items []int
+		next  string
+	}{items: []int{3}, next: "missing"}
+	_, err := iterator.Paginate(fetch).TryCollect()
+	if expected := `iterator: fetching page "missing": unknown page`; err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func Test_Prefetch(t *testing.T) {
+	var calls atomic.Int64
+	read := make(chan struct{}, 100)
+	it := iterator.FromFunc(func() (int, bool) {
+		n := int(calls.Add(1))
+		read <- struct{}{}
+		return n, n <= 10
+	}, iterator.Prefetch(3))
+	if calls.Load() != 0 {
+		t.Error("expected nothing to be read before the first call to Next")
+	}
+	if val, ok := it.Next(); !ok || val != 1 {
+		t.Errorf("expected 1, got %d", val)
+	}
+	for i := 0; i < 5; i++ { // one consumed, three buffered, and one waiting for room in the buffer
+		<-read
+	}
+	if result := it.Filter(func(val int) bool {
+		return val%2 == 0
+	}).Collect(); !reflect.DeepEqual(result, []int{2, 4, 6, 8, 10}) {
+		t.Errorf("expected [2 4 6 8 10], got %v", result)
+	}
+
+	errParse := "iterator: reading CSV: record on line 2: wrong number of fields"
+	if result, err := iterator.FromCSV(strings.NewReader("a,b\nc\n"), iterator.Prefetch(2)).TryCollect(); err == nil || err.Error() != errParse || result != nil {
+		t.Errorf("expected error %q, got %v and %v", errParse, result, err)
+	}
+
+	if result := iterator.Range(0, 3, 1, iterator.Prefetch(2)).Collect(); !reflect.DeepEqual(result, []int{0, 1, 2}) {
+		t.Errorf("expected [0 1 2], got %v", result)
+	}
+}
+
+func Test_Prefetch_Stop(t *testing.T) {
+	released := make(chan struct{})
+	stopped := make(chan struct{})
+	it := iterator.FromSeq(func(yield func(int) bool) {
+		defer close(released)
+		for i := 0; yield(i); i++ {
+		}
+	}, iterator.Prefetch(2), iterator.OnStop(func() {
+		close(stopped)
+	}))
+	if val, ok := it.Next(); !ok || val != 0 {
+		t.Errorf("expected 0, got %d", val)
+	}
+	it.Stop()
+	<-released // the sequence is stopped by the goroutine reading it
+	<-stopped
+	if val, ok := it.Next(); ok {
+		t.Errorf("expected no values after Stop, got %d", val)
+	}
+
+	stopped = make(chan struct{})
+	it = iterator.FromSeq(func(yield func(int) bool) {
+		yield(1)
+	}, iterator.Prefetch(2), iterator.OnStop(func() {
+		close(stopped)
+	}))
+	it.Collect()
+	it.Stop()
+	<-stopped // called even though the source was already exhausted
+}
+
+func Test_Clone_Stop(t *testing.T) {
+	ch := make(chan int, 1)
+	ch <- 1
+	fanIn := iterator.FanIn(ch)
+	fanIn.Next()
+	fanIn.Clone().Stop()
+	fanIn.Stop() // panicked with a close of a closed channel when clones released the source separately
+	if _, ok := fanIn.Next(); ok {
+		t.Error("expected the FanIn iterator to be stopped")
+	}
+
+	var stops int32
+	n := 0
+	prefetched := iterator.FromFunc(func() (int, bool) {
+		n++
+		return n, true
+	}, iterator.Prefetch(4), iterator.OnStop(func() {
+		atomic.AddInt32(&stops, 1)
+	}))
+	prefetched.Next()
+	clone := prefetched.Clone()
+	clone.Stop()
+	prefetched.Stop()
+	if _, ok := prefetched.Next(); ok {
+		t.Error("expected the prefetched iterator to be stopped")
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&stops) == 0 && time.Now().Before(deadline) { // released once the goroutine reading ahead stops
+		time.Sleep(time.Millisecond)
+	}
+	if s := atomic.LoadInt32(&stops); s != 1 {
+		t.Errorf("expected OnStop to be called once for an iterator and its clone, got %d calls", s)
+	}
+}
+
+func Test_OnStop(t *testing.T) {
+	for name, threadSafe := range map[string]bool{"unsynchronized": false, "thread_safe": true} {
+		t.Run(name, func(t *testing.T) {
+			ch := make(chan int)
+			done := make(chan struct{})
+			go func() { // a producer that would leak if it weren't told to stop
+				defer close(ch)
+				for i := 0; ; i++ {
+					select {
+					case ch <- i:
+					case <-done:
+						return
+					}
+				}
+			}()
+			stops := 0
+			it := iterator.FromChannel(ch, iterator.ThreadSafe(threadSafe), iterator.OnStop(func() {
+				stops++
+				close(done)
+			}))
+			if val, ok := it.Next(); !ok || val != 0 {
+				t.Errorf("expected 0, got %d", val)
+			}
+			it.Stop()
+			it.Stop()
+			if stops != 1 {
+				t.Errorf("expected the OnStop function to be called once, got %d calls", stops)
+			}
+			if val, ok := it.Next(); ok {
+				t.Errorf("expected no values after Stop, got %d", val)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package iterator
+
+import (
+	goiter "iter"
+	"math"
+	"sync"
+)
+
+// Tee returns n iterators that each return every value of the given iterator, after applying its chained operations,
+// so that the results of one pipeline can feed several consumers without reading the source more than once. The source is
+// read lazily, as the consumers ask for values, and each value is buffered until every consumer has read it, so memory
+// grows with the distance between the fastest and the slowest consumer. The iterators can be read from different
+// goroutines. Stopping one of them releases the values it hasn't read yet, and once all of them are stopped, the given
+// iterator is stopped too. As with FromFunc, Reset has no effect on the elements returned.
+func Tee[T any](it Of[T], n int) []Of[T] {
+	t := &tee[T]{src: it, pos: make([]int, n), active: n}
+	its := make([]Of[T], n)
+	for consumer := range its {
+		teed := fromFunc(func() (T, bool) {
+			return t.read(consumer)
+		}, nil)
+		teed.onStop(func() {
+			t.leave(consumer)
+		})
+		its[consumer] = teed
+	}
+	return its
+}
+
+// tee is the state shared by the iterators returned by Tee.
+type tee[T any] struct {
+	mu     sync.Mutex
+	src    Of[T]
+	next   func() (T, bool) // pulls the next value from the source, or nil until the first value is needed
+	stop   func()           // stops pulling from the source
+	done   bool             // whether the source is exhausted or stopped
+	buf    []T              // the values that haven't been read by every consumer yet
+	base   int              // the position in the stream of buf[0]
+	pos    []int            // the position in the stream of the next value each consumer reads, or math.MaxInt once it has stopped
+	active int              // the number of consumers that haven't stopped
+}
+
+func (t *tee[T]) read(consumer int) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	idx := t.pos[consumer] - t.base
+	if idx == len(t.buf) { // this consumer is the furthest ahead, so the next value has to be pulled from the source
+		if t.done {
+			return *new(T), false
+		}
+		if t.next == nil {
+			t.next, t.stop = goiter.Pull(t.src.Seq())
+		}
+		val, ok := t.next()
+		if !ok {
+			t.done = true
+			t.stop()
+			return val, false
+		}
+		t.buf = append(t.buf, val)
+	}
+	val := t.buf[idx]
+	t.pos[consumer]++
+	t.release()
+	return val, true
+}
+
+// leave stops a consumer, releasing the values it hasn't read. Once every consumer has left, the source is stopped.
+func (t *tee[T]) leave(consumer int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pos[consumer] = math.MaxInt
+	t.release()
+	if t.active--; t.active <= 0 && !t.done {
+		t.done = true
+		if t.stop != nil {
+			t.stop()
+		}
+		t.src.Stop()
+	}
+}
+
+// release drops the buffered values every consumer has read.
+func (t *tee[T]) release() {
+	slowest := math.MaxInt
+	for _, pos := range t.pos {
+		slowest = minInt(slowest, pos)
+	}
+	drop := minInt(slowest-t.base, len(t.buf))
+	if drop <= 0 {
+		return
+	}
+	clear(t.buf[:drop]) // let the dropped values be garbage collected
+	t.buf = t.buf[drop:]
+	t.base += drop
+}
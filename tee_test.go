@@ -0,0 +1,73 @@
+package iterator_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_Tee(t *testing.T) {
+	reads := 0
+	source := iterator.FromFunc(func() (int, bool) {
+		reads++
+		return reads, reads <= 5
+	}).Map(func(val int) int {
+		return val * 10
+	})
+	its := iterator.Tee(source, 2)
+	sum := its[1].Reduce(func(acc, val int) int { // reads the whole source before the other consumer starts
+		return acc + val
+	}, 0)
+	if sum != 150 {
+		t.Errorf("expected a sum of 150, got %d", sum)
+	}
+	if result := its[0].Collect(); !reflect.DeepEqual(result, []int{10, 20, 30, 40, 50}) {
+		t.Errorf("expected [10 20 30 40 50], got %v", result)
+	}
+	if reads != 6 {
+		t.Errorf("expected the source to be read once, got %d reads", reads)
+	}
+}
+
+func Test_Tee_Concurrent(t *testing.T) {
+	its := iterator.Tee(iterator.Range(0, 1000, 1), 4)
+	results := make([][]int, len(its))
+	wg := sync.WaitGroup{}
+	for i, it := range its {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = it.Collect()
+		}()
+	}
+	wg.Wait()
+	expected := iterator.Range(0, 1000, 1).Collect()
+	for i, result := range results {
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected consumer %d to see every value, got %d values", i, len(result))
+		}
+	}
+}
+
+func Test_Tee_Stop(t *testing.T) {
+	stopped := false
+	source := iterator.FromFunc(func() (int, bool) {
+		return 1, true
+	}, iterator.OnStop(func() {
+		stopped = true
+	}))
+	its := iterator.Tee(source, 2)
+	its[0].Stop()
+	if val, ok := its[1].Next(); !ok || val != 1 {
+		t.Errorf("expected the other consumer to keep reading, got %d", val)
+	}
+	if stopped {
+		t.Error("expected the source to keep going while a consumer is left")
+	}
+	its[1].Stop()
+	if !stopped {
+		t.Error("expected the source to be stopped once every consumer has stopped")
+	}
+}
@@ -0,0 +1,247 @@
+package iterator
+
+import (
+	"cmp"
+	"fmt"
+	goiter "iter"
+	"math"
+	"strings"
+)
+
+// CollectMap applies all of the chained operations to the iterator and builds a map from the values left, using keyFn and
+// valFn to extract the key and value of each entry. By default, a later value overwrites an earlier one with the same key;
+// see the Duplicates option for the other policies. If the DuplicatesError policy is used and any keys are duplicated, the
+// whole iterator is still consumed so that every conflict can be reported at once in a *DuplicateKeyError, and the
+// returned map is nil.
+func CollectMap[T any, K comparable, V any](it Of[T], keyFn func(T) K, valFn func(T) V, opts ...MapOption) (map[K]V, error) {
+	return Associate(it, func(val T) (K, V) {
+		return keyFn(val), valFn(val)
+	}, opts...)
+}
+
+// Associate is like CollectMap, but uses a single function returning both the key and the value of each entry, which suits
+// keys and values derived from the same computation. The Duplicates option works the same way.
+func Associate[T any, K comparable, V any](it Of[T], fn func(T) (K, V), opts ...MapOption) (map[K]V, error) {
+	options := new(mapOptions)
+	for _, opt := range opts {
+		opt(options)
+	}
+	result := make(map[K]V)
+	firstIndex := make(map[K]int) // only tracked for the error policy, to report where each conflicting key first appeared
+	conflicts := make(map[K][]int)
+	var order []K // the order keys were first found to conflict in, so the error is deterministic
+	idx := 0
+	for val := range it.Seq() {
+		key, value := fn(val)
+		switch options.duplicates {
+		case DuplicatesError:
+			if conflicts[key] != nil {
+				conflicts[key] = append(conflicts[key], idx)
+			} else if first, ok := firstIndex[key]; ok {
+				conflicts[key] = []int{first, idx}
+				order = append(order, key)
+			} else {
+				firstIndex[key] = idx
+				result[key] = value
+			}
+		case DuplicatesKeepFirst:
+			if _, ok := result[key]; !ok {
+				result[key] = value
+			}
+		default:
+			result[key] = value
+		}
+		idx++
+	}
+	if len(order) > 0 {
+		err := &DuplicateKeyError[K]{Conflicts: make([]KeyConflict[K], 0, len(order))}
+		for _, key := range order {
+			err.Conflicts = append(err.Conflicts, KeyConflict[K]{Key: key, Indexes: conflicts[key]})
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// KeyConflict describes a key that was produced by more than one value.
+type KeyConflict[K comparable] struct {
+	Key     K     // the duplicated key
+	Indexes []int // the indexes of the values that produced the key, counting only the values left after the chained operations
+}
+
+// DuplicateKeyError is returned when building a map using the DuplicatesError policy and some keys are duplicated. It
+// reports every conflicting key, in the order the conflicts were found, so data-quality issues can be fixed in one go.
+type DuplicateKeyError[K comparable] struct {
+	Conflicts []KeyConflict[K]
+}
+
+func (e *DuplicateKeyError[K]) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("%v at indexes %v", c.Key, c.Indexes))
+	}
+	return fmt.Sprintf("iterator: %d duplicate keys: %s", len(e.Conflicts), strings.Join(conflicts, ", "))
+}
+
+// Frequencies applies all of the chained operations to the iterator and counts the occurrences of each value left.
+func Frequencies[T comparable](it Of[T]) map[T]int {
+	counts := make(map[T]int)
+	for val := range it.Seq() {
+		counts[val]++
+	}
+	return counts
+}
+
+// ReduceBy applies all of the chained operations to the iterator and reduces the values left separately for each key
+// returned by keyFn, in a single pass. Each key's accumulator starts as initial and is passed to reduceFn along with each
+// value with that key, in the order the values are produced, as Reduce does for the whole iterator. Only one accumulator
+// per key is held in memory, rather than every value of each group.
+func ReduceBy[T any, K comparable, A any](it Of[T], keyFn func(T) K, reduceFn func(A, T) A, initial A) map[K]A {
+	result := make(map[K]A)
+	for val := range it.Seq() {
+		key := keyFn(val)
+		acc, ok := result[key]
+		if !ok {
+			acc = initial
+		}
+		result[key] = reduceFn(acc, val)
+	}
+	return result
+}
+
+// IsSorted applies all of the chained operations to the iterator and reports whether the values left are in ascending
+// order, allowing equal values next to each other. It stops at the first value that's out of order, leaving the rest of
+// the source unread.
+func IsSorted[T Ordered](it Of[T]) bool {
+	return IsSortedBy(it, func(a, b T) bool {
+		return a < b
+	})
+}
+
+// IsSortedBy is like IsSorted, but uses the given function to determine whether a should come before b, as Sort does, so
+// the values are sorted if Sort would leave them in the same order.
+func IsSortedBy[T any](it Of[T], less func(a, b T) bool) bool {
+	var prev T
+	first := true
+	for val := range it.Seq() {
+		if !first && less(val, prev) {
+			return false
+		}
+		prev, first = val, false
+	}
+	return true
+}
+
+// Equal applies the chained operations of both iterators and reports whether they produce the same values in the same
+// order. The iterators are read side by side, one value at a time, and reading stops at the first difference.
+func Equal[T comparable](a, b Of[T]) bool {
+	return CompareFunc(a, b, func(x, y T) int {
+		if x == y {
+			return 0
+		}
+		return 1
+	}) == 0
+}
+
+// Compare applies the chained operations of both iterators and compares the values they produce lexicographically, as
+// slices.Compare does: the first pair of values that differ decides the result, and if one iterator runs out first, it's
+// the lesser. The result is 0 if a == b, -1 if a < b, and +1 if a > b. Reading stops at the first difference.
+func Compare[T Ordered](a, b Of[T]) int {
+	return CompareFunc(a, b, cmp.Compare[T])
+}
+
+// CompareFunc is like Compare, but uses the given function to compare each pair of values, which should return a negative
+// number if x < y, a positive number if x > y, and 0 if they are equal.
+func CompareFunc[T any](a, b Of[T], compare func(x, y T) int) int {
+	nextA, stopA := goiter.Pull(a.Seq())
+	defer stopA()
+	nextB, stopB := goiter.Pull(b.Seq())
+	defer stopB()
+	for {
+		x, okA := nextA()
+		y, okB := nextB()
+		switch {
+		case !okA && !okB:
+			return 0
+		case !okA:
+			return -1
+		case !okB:
+			return +1
+		}
+		if c := compare(x, y); c != 0 {
+			return c
+		}
+	}
+}
+
+// Statistics summarizes the values of a numeric iterator, as returned by Stats.
+type Statistics[T Number] struct {
+	Count  int     // the number of values
+	Sum    T       // the sum of the values, which can overflow T like any other sum
+	Min    T       // the least value, or zero if there were no values
+	Max    T       // the greatest value, or zero if there were no values
+	Mean   float64 // the arithmetic mean of the values, or zero if there were no values
+	StdDev float64 // the population standard deviation of the values, or zero if there were no values
+}
+
+// Stats applies all of the chained operations to the iterator and summarizes the values left in a single pass, without
+// holding them in memory. The mean and standard deviation are computed using Welford's algorithm, so they stay accurate
+// for long runs of large values.
+func Stats[T Number](it Of[T]) Statistics[T] {
+	var stats Statistics[T]
+	var m2 float64 // the sum of the squared differences from the mean so far
+	for val := range it.Seq() {
+		if stats.Count == 0 || val < stats.Min {
+			stats.Min = val
+		}
+		if stats.Count == 0 || val > stats.Max {
+			stats.Max = val
+		}
+		stats.Count++
+		stats.Sum += val
+		delta := float64(val) - stats.Mean
+		stats.Mean += delta / float64(stats.Count)
+		m2 += delta * (float64(val) - stats.Mean)
+	}
+	if stats.Count > 0 {
+		stats.StdDev = math.Sqrt(m2 / float64(stats.Count))
+	}
+	return stats
+}
+
+// Watch builds and collects a fresh pipeline every time the trigger fires, passing the results to sink, until the trigger is
+// closed. This is a minimal recomputation loop for values derived from a changing source, such as a cache refreshed on a
+// timer or whenever a config file changes. As the pipeline is only run on a trigger, send one up front if the results are
+// needed straight away. Watch blocks until the trigger is closed, so it's usually run on its own goroutine. Each run
+// finishes before the next trigger is received, so triggers that fire during a run are coalesced by the channel's buffer.
+func Watch[T any](build func() Of[T], trigger <-chan struct{}, sink func([]T)) {
+	for range trigger {
+		sink(build().Collect())
+	}
+}
+
+// GroupConsecutive applies all of the chained operations to the iterator and groups consecutive values with the same key,
+// passing each group to flush as soon as a value with a different key is found. If the values are sorted by key, each key's
+// group is complete when it's flushed, so huge sorted exports can be grouped while only holding one group in memory. If
+// maxLen is at least 1, a group is also flushed once it reaches maxLen values, so the same key can be flushed more than
+// once. The group slice is reused between calls, so flush must copy any values it keeps. If flush returns an error,
+// grouping stops and the error is returned.
+func GroupConsecutive[T any, K comparable](it Of[T], key func(T) K, maxLen int, flush func(key K, group []T) error) error {
+	var current K
+	var group []T
+	for val := range it.Seq() {
+		k := key(val)
+		if len(group) > 0 && (k != current || len(group) == maxLen) {
+			if err := flush(current, group); err != nil {
+				return err
+			}
+			group = group[:0]
+		}
+		current = k
+		group = append(group, val)
+	}
+	if len(group) > 0 {
+		return flush(current, group)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+package iterator_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thezmc/iterator"
+)
+
+type user struct {
+	id   int
+	name string
+}
+
+func Test_CollectMap(t *testing.T) {
+	users := []user{{1, "ana"}, {2, "beto"}, {1, "ana maría"}}
+	byID, err := iterator.CollectMap(iterator.From(users), func(u user) int {
+		return u.id
+	}, func(u user) string {
+		return u.name
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(byID, map[int]string{1: "ana maría", 2: "beto"}) {
+		t.Errorf("expected map[1:ana maría 2:beto], got %v", byID)
+	}
+}
+
+func Test_Associate(t *testing.T) {
+	users := []user{{1, "ana"}, {2, "beto"}, {1, "ana maría"}}
+	names := func(u user) (int, string) {
+		return u.id, u.name
+	}
+	tests := map[string]struct {
+		policy   iterator.DuplicatePolicy
+		expected map[int]string
+	}{
+		"keep last": {
+			policy:   iterator.DuplicatesOverwrite,
+			expected: map[int]string{1: "ana maría", 2: "beto"},
+		},
+		"keep first": {
+			policy:   iterator.DuplicatesKeepFirst,
+			expected: map[int]string{1: "ana", 2: "beto"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := iterator.Associate(iterator.From(users), names, iterator.Duplicates(test.policy))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, result)
+			}
+		})
+	}
+
+	first, _ := iterator.CollectMap(iterator.From(users), func(u user) int {
+		return u.id
+	}, func(u user) string {
+		return u.name
+	}, iterator.Duplicates(iterator.DuplicatesKeepFirst))
+	if !reflect.DeepEqual(first, map[int]string{1: "ana", 2: "beto"}) {
+		t.Errorf("expected map[1:ana 2:beto], got %v", first)
+	}
+	if _, err := iterator.Associate(iterator.From(users), names, iterator.Duplicates(iterator.DuplicatesError)); err == nil {
+		t.Error("expected the duplicated key to be reported")
+	}
+}
+
+func Test_CollectMap_DuplicatesError(t *testing.T) {
+	users := []user{{1, "a"}, {2, "b"}, {1, "c"}, {3, "skip"}, {2, "d"}, {1, "e"}}
+	it := iterator.From(users).Filter(func(u user) bool {
+		return u.name != "skip"
+	})
+	byID, err := iterator.CollectMap(it, func(u user) int {
+		return u.id
+	}, func(u user) user {
+		return u
+	}, iterator.Duplicates(iterator.DuplicatesError))
+	if byID != nil {
+		t.Errorf("expected no map, got %v", byID)
+	}
+	var dupErr *iterator.DuplicateKeyError[int]
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected a DuplicateKeyError, got %v", err)
+	}
+	expected := []iterator.KeyConflict[int]{{Key: 1, Indexes: []int{0, 2, 4}}, {Key: 2, Indexes: []int{1, 3}}}
+	if !reflect.DeepEqual(dupErr.Conflicts, expected) {
+		t.Errorf("expected %+v, got %+v", expected, dupErr.Conflicts)
+	}
+	if msg := err.Error(); msg != "iterator: 2 duplicate keys: 1 at indexes [0 2 4], 2 at indexes [1 3]" {
+		t.Errorf("unexpected error message %q", msg)
+	}
+}
+
+func Test_Frequencies(t *testing.T) {
+	tests := map[string]struct {
+		source   []string
+		expected map[string]int
+	}{
+		"counts": {[]string{"get", "post", "get", "GET", "get"}, map[string]int{"get": 3, "post": 1}},
+		"empty":  {[]string{}, map[string]int{}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			counts := iterator.Frequencies(iterator.From(test.source).Filter(func(method string) bool {
+				return method != "GET"
+			}))
+			if !reflect.DeepEqual(counts, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, counts)
+			}
+		})
+	}
+}
+
+func Test_ReduceBy(t *testing.T) {
+	type order struct {
+		customer string
+		amount   int
+	}
+	orders := []order{{"ana", 10}, {"beto", 5}, {"ana", 7}, {"carla", 0}, {"beto", 1}}
+	totals := iterator.ReduceBy(iterator.From(orders).Filter(func(o order) bool {
+		return o.amount > 0
+	}), func(o order) string {
+		return o.customer
+	}, func(total int, o order) int {
+		return total + o.amount
+	}, 100)
+	if expected := map[string]int{"ana": 117, "beto": 106}; !reflect.DeepEqual(totals, expected) {
+		t.Errorf("expected %+v, got %+v", expected, totals)
+	}
+	if result := iterator.ReduceBy(iterator.Empty[order](), func(o order) string {
+		return o.customer
+	}, func(n int, _ order) int {
+		return n + 1
+	}, 0); !reflect.DeepEqual(result, map[string]int{}) {
+		t.Errorf("expected an empty map, got %+v", result)
+	}
+}
+
+func Test_IsSorted(t *testing.T) {
+	tests := map[string]struct {
+		source   []int
+		expected bool
+	}{
+		"ascending":   {source: []int{1, 2, 2, 5}, expected: true},
+		"unsorted":    {source: []int{1, 3, 2, 5}, expected: false},
+		"single":      {source: []int{7}, expected: true},
+		"empty":       {source: []int{}, expected: true},
+		"descending":  {source: []int{3, 2, 1}, expected: false},
+		"equal only":  {source: []int{4, 4, 4}, expected: true},
+		"late change": {source: []int{1, 2, 3, 4, 0}, expected: false},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := iterator.IsSorted(iterator.From(test.source)); result != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, result)
+			}
+		})
+	}
+
+	it := iterator.From([]int{3, 2, 4, 1})
+	if iterator.IsSortedBy(it, func(a, b int) bool { return a > b }) {
+		t.Error("expected [3 2 4 1] not to be sorted in descending order")
+	}
+	if rest := it.Collect(); !reflect.DeepEqual(rest, []int{1}) {
+		t.Errorf("expected IsSortedBy to stop at the first value out of order, leaving [1], got %v", rest)
+	}
+}
+
+func Test_Equal_Compare(t *testing.T) {
+	tests := map[string]struct {
+		a, b     []int
+		expected int
+	}{
+		"equal":         {a: []int{1, 2, 3}, b: []int{1, 2, 3}, expected: 0},
+		"both empty":    {a: []int{}, b: []int{}, expected: 0},
+		"less":          {a: []int{1, 2, 3}, b: []int{1, 3}, expected: -1},
+		"greater":       {a: []int{2}, b: []int{1, 5}, expected: +1},
+		"shorter":       {a: []int{1, 2}, b: []int{1, 2, 3}, expected: -1},
+		"longer":        {a: []int{1, 2, 3}, b: []int{1, 2}, expected: +1},
+		"empty vs some": {a: []int{}, b: []int{1}, expected: -1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := iterator.Compare(iterator.From(test.a), iterator.From(test.b)); result != test.expected {
+				t.Errorf("expected %d, got %d", test.expected, result)
+			}
+			if result := iterator.Equal(iterator.From(test.a), iterator.From(test.b)); result != (test.expected == 0) {
+				t.Errorf("expected Equal to return %t, got %t", test.expected == 0, result)
+			}
+		})
+	}
+
+	read := 0
+	infinite := iterator.FromFunc(func() (int, bool) {
+		read++
+		return read, true
+	})
+	if iterator.Equal(iterator.From([]int{1, 2, 4}), infinite) {
+		t.Error("expected [1 2 4] to differ from the natural numbers")
+	}
+	if read != 3 {
+		t.Errorf("expected reading to stop at the first difference, got %d reads", read)
+	}
+	byLength := func(x, y string) int {
+		return len(x) - len(y)
+	}
+	if result := iterator.CompareFunc(iterator.From([]string{"ab", "c"}), iterator.From([]string{"xy", "zz"}), byLength); result >= 0 {
+		t.Errorf("expected a negative result, got %d", result)
+	}
+}
+
+func Test_Stats(t *testing.T) {
+	stats := iterator.Stats(iterator.FromValues(2, 4, 4, 4, 5, 5, 7, 9, -100).Filter(func(val int) bool {
+		return val > 0
+	}))
+	expected := iterator.Statistics[int]{Count: 8, Sum: 40, Min: 2, Max: 9, Mean: 5, StdDev: 2}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	if stats := iterator.Stats(iterator.Empty[float64]()); stats != (iterator.Statistics[float64]{}) {
+		t.Errorf("expected zero statistics, got %+v", stats)
+	}
+	floats := iterator.Stats(iterator.FromValues(1e9+0.5, 1e9+1.5))
+	if floats.Mean != 1e9+1 || floats.StdDev != 0.5 || floats.Min != 1e9+0.5 || floats.Max != 1e9+1.5 {
+		t.Errorf("unexpected statistics %+v", floats)
+	}
+}
+
+func Test_Watch(t *testing.T) {
+	source := []int{1, 2}
+	trigger := make(chan struct{})
+	results := make(chan []int)
+	go func() {
+		defer close(results)
+		iterator.Watch(func() iterator.Of[int] {
+			return iterator.From(source).Map(func(val int) int {
+				return val * 10
+			})
+		}, trigger, func(result []int) {
+			results <- result
+		})
+	}()
+	trigger <- struct{}{}
+	if result := <-results; !reflect.DeepEqual(result, []int{10, 20}) {
+		t.Errorf("expected [10 20], got %v", result)
+	}
+	source = append(source, 3)
+	trigger <- struct{}{}
+	if result := <-results; !reflect.DeepEqual(result, []int{10, 20, 30}) {
+		t.Errorf("expected [10 20 30], got %v", result)
+	}
+	close(trigger)
+	if _, ok := <-results; ok {
+		t.Error("expected Watch to return once the trigger is closed")
+	}
+}
+
+func Test_GroupConsecutive(t *testing.T) {
+	type group struct {
+		key  string
+		vals []int
+	}
+	source := []user{{1, "a"}, {2, "a"}, {3, "b"}, {4, "b"}, {5, "b"}, {6, "a"}}
+	tests := map[string]struct {
+		maxLen   int
+		expected []group
+	}{
+		"unbounded": {0, []group{{"a", []int{1, 2}}, {"b", []int{3, 4, 5}}, {"a", []int{6}}}},
+		"bounded":   {2, []group{{"a", []int{1, 2}}, {"b", []int{3, 4}}, {"b", []int{5}}, {"a", []int{6}}}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var groups []group
+			err := iterator.GroupConsecutive(iterator.From(source), func(u user) string {
+				return u.name
+			}, test.maxLen, func(key string, users []user) error {
+				ids := make([]int, 0, len(users))
+				for _, u := range users {
+					ids = append(ids, u.id)
+				}
+				groups = append(groups, group{key, ids})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !reflect.DeepEqual(groups, test.expected) {
+				t.Errorf("expected %+v, got %+v", test.expected, groups)
+			}
+		})
+	}
+
+	errStop := errors.New("stop")
+	flushed := 0
+	err := iterator.GroupConsecutive(iterator.From(source), func(u user) string {
+		return u.name
+	}, 0, func(string, []user) error {
+		flushed++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || flushed != 1 {
+		t.Errorf("expected grouping to stop at the first error, got %v after %d groups", err, flushed)
+	}
+}
@@ -0,0 +1,77 @@
+package iterator
+
+import (
+	"context"
+	"time"
+)
+
+// FromTicker returns a new iterator over the times of the ticks of a ticker with the given period, until the context is
+// cancelled, such as to drive a pipeline that polls a resource at a fixed rate. Calls to Next block until the next tick,
+// and as with time.Ticker, ticks are dropped if the iterator is read too slowly. The ticker is stopped once the context is
+// cancelled or the Stop method is called. As ticks can't be replayed, Reset has no effect on the elements returned. The
+// options are the same as for From, although CopySource has no effect. FromTicker panics if d isn't positive.
+func FromTicker(ctx context.Context, d time.Duration, opts ...FromOption) Of[time.Time] {
+	ticker := time.NewTicker(d)
+	it := fromFunc(func() (time.Time, bool) {
+		if ctx.Err() != nil { // checked first, as select picks randomly if a tick is also ready
+			ticker.Stop()
+			return time.Time{}, false
+		}
+		select {
+		case tick := <-ticker.C:
+			return tick, true
+		case <-ctx.Done():
+			ticker.Stop()
+			return time.Time{}, false
+		}
+	}, opts)
+	it.onStop(ticker.Stop)
+	return it
+}
+
+// WindowByTime returns a new iterator over tumbling windows of the values left after applying the chained operations of
+// the given iterator: each window holds the values received during a period of d, starting when the first value is
+// requested, so a stream can be processed in batches, such as counting events per second from a FromChannel source.
+// Windows without any values are skipped, and the window open when the source is exhausted is returned with the values
+// received so far. The source is read on its own goroutine, so windows are closed on time even while the source is
+// waiting for its next value; stopping the iterator early leaves that goroutine waiting until the source returns its next
+// value. As with FromFunc, Reset has no effect on the elements returned.
+func WindowByTime[T any](it Of[T], d time.Duration) Of[[]T] {
+	return FromSeq(func(yield func([]T) bool) {
+		vals := make(chan T)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			defer close(vals)
+			for val := range it.Seq() {
+				select {
+				case vals <- val:
+				case <-done:
+					return
+				}
+			}
+		}()
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		var window []T
+		for {
+			select {
+			case val, ok := <-vals:
+				if !ok {
+					if len(window) > 0 {
+						yield(window)
+					}
+					return
+				}
+				window = append(window, val)
+			case <-ticker.C:
+				if len(window) > 0 {
+					if !yield(window) {
+						return
+					}
+					window = nil
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package iterator_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thezmc/iterator"
+)
+
+func Test_FromTicker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ticks := 0
+	var last time.Time
+	for tick := range iterator.FromTicker(ctx, time.Millisecond).Seq() {
+		if !tick.After(last) {
+			t.Errorf("expected ticks to be increasing, got %v after %v", tick, last)
+		}
+		last = tick
+		if ticks++; ticks == 3 {
+			cancel()
+		}
+	}
+	if ticks != 3 {
+		t.Errorf("expected the iterator to end once cancelled, got %d ticks", ticks)
+	}
+
+	it := iterator.FromTicker(context.Background(), time.Millisecond)
+	it.Next()
+	it.Stop()
+	if _, ok := it.Next(); ok {
+		t.Error("expected no ticks after Stop")
+	}
+}
+
+func Test_WindowByTime(t *testing.T) {
+	const period = 50 * time.Millisecond
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= 3; i++ {
+			ch <- i
+		}
+		time.Sleep(3 * period) // let the first window close, and a few empty ones pass
+		ch <- 4
+		ch <- 5
+	}()
+	windows := iterator.WindowByTime(iterator.FromChannel(ch).Map(func(val int) int {
+		return val * 10
+	}), period).Collect()
+	if expected := [][]int{{10, 20, 30}, {40, 50}}; !reflect.DeepEqual(windows, expected) {
+		t.Errorf("expected %v, got %v", expected, windows)
+	}
+	if windows := iterator.WindowByTime(iterator.Empty[int](), period).Collect(); len(windows) != 0 {
+		t.Errorf("expected no windows, got %v", windows)
+	}
+}